@@ -0,0 +1,136 @@
+// admin 为运维命令行工具，直接连接 Redis 执行管理任务。
+//
+// 用法：
+//
+//	admin export -code a3K9mP2x -type clicks -format ndjson -from 2026-01-01 -to 2026-01-31 -out clicks.ndjson
+//	admin export -code a3K9mP2x -type stats -interval day -format csv
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	redisv9 "github.com/redis/go-redis/v9"
+
+	"url-shortener/backend/internal/export"
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/service"
+	storageredis "url-shortener/backend/internal/storage/redis"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch os.Args[1] {
+	case "export":
+		if err := runExport(ctx, os.Args[2:]); err != nil {
+			log.Fatalf("export failed: %v", err)
+		}
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: admin export -code CODE [-type clicks|stats] [-format csv|ndjson] [-from DATE] [-to DATE] [-interval hour|day] [-out FILE]")
+	os.Exit(2)
+}
+
+// runExport 导出单个短链接的原始点击事件或统计桶，默认写到标准输出
+func runExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	code := fs.String("code", "", "short code to export")
+	kind := fs.String("type", "clicks", "what to export: clicks or stats")
+	req := service.ExportRequest{}
+	fs.StringVar(&req.Format, "format", export.FormatCSV, "output format: csv or ndjson")
+	fs.StringVar(&req.From, "from", "", "range start, RFC3339 or YYYY-MM-DD")
+	fs.StringVar(&req.To, "to", "", "range end, RFC3339 or YYYY-MM-DD")
+	fs.StringVar(&req.Interval, "interval", model.StatsIntervalDay, "stats bucket size: hour or day")
+	outPath := fs.String("out", "", "output file (default stdout)")
+	_ = fs.Parse(args)
+	if *code == "" {
+		return fmt.Errorf("-code is required")
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		f, err := os.Create(*outPath)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = f.Close() }()
+		out = f
+	}
+	buffered := bufio.NewWriter(out)
+	defer func() { _ = buffered.Flush() }()
+
+	w, err := export.NewWriter(buffered, req.Format)
+	if err != nil {
+		return err
+	}
+
+	rdb := redisv9.NewClient(&redisv9.Options{
+		Addr:     envOr("REDIS_ADDR", "localhost:6379"),
+		Password: os.Getenv("REDIS_PASSWORD"),
+	})
+	defer func() { _ = rdb.Close() }()
+	if err := rdb.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to connect to redis: %w", err)
+	}
+
+	svc := service.NewLinkService(
+		storageredis.NewRepository(rdb),
+		envOr("BASE_URL", "http://localhost:8080"),
+		service.WithClickRecorder(storageredis.NewClickRepository(rdb)),
+	)
+	defer func() { _ = svc.Close(context.Background()) }()
+
+	switch *kind {
+	case "clicks":
+		if err := w.WriteClickHeader(); err != nil {
+			return err
+		}
+		if err := svc.ExportClicks(ctx, *code, &req, w.WriteClick); err != nil {
+			return err
+		}
+	case "stats":
+		stats, err := svc.ExportStats(ctx, *code, &req)
+		if err != nil {
+			return err
+		}
+		if err := w.WriteBucketHeader(); err != nil {
+			return err
+		}
+		for _, bucket := range stats.Buckets {
+			if err := w.WriteBucket(stats.Code, stats.Interval, bucket); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("-type must be clicks or stats")
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -2,17 +2,29 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
+	_ "time/tzdata" // 内置时区数据，运行镜像（alpine）中没有 zoneinfo
 
 	"github.com/gin-gonic/gin"
 	redisv9 "github.com/redis/go-redis/v9"
 
+	"url-shortener/backend/internal/analytics"
+	"url-shortener/backend/internal/botdetect"
+	"url-shortener/backend/internal/events"
 	"url-shortener/backend/internal/handler"
+	"url-shortener/backend/internal/health"
+	"url-shortener/backend/internal/metadata"
 	"url-shortener/backend/internal/service"
 	storageredis "url-shortener/backend/internal/storage/redis"
+	"url-shortener/backend/internal/webhook"
 )
 
 func main() {
@@ -34,6 +46,149 @@ func main() {
 		baseURL = "http://localhost:8080"
 	}
 
+	// 全局备用地址：短链接不存在或不可用时跳转（为空则返回错误）
+	fallbackURL := os.Getenv("FALLBACK_URL")
+
+	// 是否在创建后抓取目标页面元数据（默认开启）
+	metadataFetchEnabled := os.Getenv("METADATA_FETCH_ENABLED") != "false"
+
+	// 目标地址健康检查间隔与并发数
+	healthCheckInterval := time.Minute
+	if v := os.Getenv("HEALTH_CHECK_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Fatalf("invalid HEALTH_CHECK_INTERVAL: %q", v)
+		}
+		healthCheckInterval = d
+	}
+	healthCheckConcurrency := 8
+	if v := os.Getenv("HEALTH_CHECK_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid HEALTH_CHECK_CONCURRENCY: %q", v)
+		}
+		healthCheckConcurrency = n
+	}
+
+	// CDN/反向代理写入访问者国家代码的请求头（如 Cloudflare 的 CF-IPCountry），为空则不统计国家
+	countryHeader := os.Getenv("GEO_COUNTRY_HEADER")
+	// 可信反向代理（逗号分隔的 IP / CIDR），只采信它们转发的 X-Forwarded-For；默认直接使用连接地址
+	trustedProxies := splitList(os.Getenv("TRUSTED_PROXIES"))
+
+	// 机器人识别的附加规则文件（每行一个 User-Agent 特征），收到 SIGHUP 时重新加载
+	botClassifier, err := botdetect.NewClassifier(os.Getenv("BOT_RULES_FILE"))
+	if err != nil {
+		log.Fatalf("failed to load bot rules: %v", err)
+	}
+
+	// 访客数据处理策略（IP 匿名化、密钥轮换、DNT、原始事件保留天数）
+	privacy := service.DefaultPrivacyPolicy()
+	if v := os.Getenv("PRIVACY_IP_MODE"); v != "" {
+		privacy.IPMode = v
+	}
+	if v := os.Getenv("PRIVACY_IPV4_PREFIX"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid PRIVACY_IPV4_PREFIX: %q", v)
+		}
+		privacy.IPv4PrefixBits = n
+	}
+	if v := os.Getenv("PRIVACY_IPV6_PREFIX"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid PRIVACY_IPV6_PREFIX: %q", v)
+		}
+		privacy.IPv6PrefixBits = n
+	}
+	privacy.RotateSalt = os.Getenv("PRIVACY_ROTATE_SALT") == "true"
+	privacy.HonorDoNotTrack = os.Getenv("PRIVACY_HONOR_DNT") != "false"
+	if v := os.Getenv("CLICK_RETENTION_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			log.Fatalf("invalid CLICK_RETENTION_DAYS: %q", v)
+		}
+		privacy.ClickRetention = time.Duration(n) * 24 * time.Hour
+	}
+	if err := privacy.Validate(); err != nil {
+		log.Fatalf("invalid privacy settings: %v", err)
+	}
+
+	// 点击事件管道的缓冲长度与 worker 数
+	clickBufferSize := 10000
+	if v := os.Getenv("CLICK_PIPELINE_BUFFER"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid CLICK_PIPELINE_BUFFER: %q", v)
+		}
+		clickBufferSize = n
+	}
+	clickWorkers := 4
+	if v := os.Getenv("CLICK_PIPELINE_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid CLICK_PIPELINE_WORKERS: %q", v)
+		}
+		clickWorkers = n
+	}
+
+	// 转化归因窗口（天）
+	attributionWindowDays := 30
+	if v := os.Getenv("ATTRIBUTION_WINDOW_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid ATTRIBUTION_WINDOW_DAYS: %q", v)
+		}
+		attributionWindowDays = n
+	}
+
+	// webhook 投递的并发数、最多尝试次数，以及是否允许推送到内网地址
+	webhookConcurrency := 4
+	if v := os.Getenv("WEBHOOK_CONCURRENCY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid WEBHOOK_CONCURRENCY: %q", v)
+		}
+		webhookConcurrency = n
+	}
+	webhookMaxAttempts := 10
+	if v := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid WEBHOOK_MAX_ATTEMPTS: %q", v)
+		}
+		webhookMaxAttempts = n
+	}
+	webhookAllowPrivate := os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS") == "true"
+
+	// 领域事件 sink：逗号分隔的 file / redis / http，为空时不投递（outbox 仍会写入）
+	eventSinkNames := splitList(os.Getenv("EVENT_SINKS"))
+	eventFileDir := os.Getenv("EVENT_FILE_DIR")
+	if eventFileDir == "" {
+		eventFileDir = "events"
+	}
+	eventFileMaxMB := 100
+	if v := os.Getenv("EVENT_FILE_MAX_MB"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid EVENT_FILE_MAX_MB: %q", v)
+		}
+		eventFileMaxMB = n
+	}
+	eventFileMaxFiles := 10
+	if v := os.Getenv("EVENT_FILE_MAX_FILES"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			log.Fatalf("invalid EVENT_FILE_MAX_FILES: %q", v)
+		}
+		eventFileMaxFiles = n
+	}
+	eventRedisStream := os.Getenv("EVENT_REDIS_STREAM")
+	if eventRedisStream == "" {
+		eventRedisStream = "shortener:events"
+	}
+	eventHTTPURL := os.Getenv("EVENT_HTTP_URL")
+	eventHTTPSecret := os.Getenv("EVENT_HTTP_SECRET")
+
 	// 初始化 Redis
 	rdb, err := initRedis(redisAddr, redisPassword, redisDB)
 	if err != nil {
@@ -44,11 +199,97 @@ func main() {
 	// 初始化 Repository
 	repo := storageredis.NewRepository(rdb)
 
+	// 启动后台健康检查
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go health.NewChecker(repo, health.Options{
+		Interval:    healthCheckInterval,
+		Concurrency: healthCheckConcurrency,
+	}).Run(ctx)
+
 	// 初始化 Service
-	linkService := service.NewLinkService(repo, baseURL)
+	// 实时点击分发：每个实例通过 Redis pub/sub 订阅一次，再分发给本实例的 SSE 连接
+	clickRepo := storageredis.NewClickRepository(rdb)
+	liveHub := analytics.NewHub(clickRepo)
+	liveCtx, liveCancel := context.WithCancel(ctx)
+	defer liveCancel()
+	go liveHub.Run(liveCtx)
+
+	// webhook 投递队列保存在 Redis 中，重启后继续投递未完成的推送
+	webhookRepo := storageredis.NewWebhookRepository(rdb)
+	go webhook.NewDispatcher(webhookRepo, webhook.Options{
+		Concurrency:          webhookConcurrency,
+		MaxAttempts:          webhookMaxAttempts,
+		AllowPrivateNetworks: webhookAllowPrivate,
+	}).Run(ctx)
+
+	// 领域事件与触发它的写操作一起写入 outbox，由 relay 按 sink 分别投递并确认
+	var eventSinks []events.Sink
+	for _, name := range eventSinkNames {
+		switch name {
+		case "file":
+			fileSink, err := events.NewFileSink(events.FileOptions{
+				Dir:      eventFileDir,
+				MaxBytes: int64(eventFileMaxMB) << 20,
+				MaxFiles: eventFileMaxFiles,
+			})
+			if err != nil {
+				log.Fatalf("failed to open event file: %v", err)
+			}
+			defer func() { _ = fileSink.Close() }()
+			eventSinks = append(eventSinks, fileSink)
+		case "redis":
+			eventSinks = append(eventSinks, events.NewRedisStreamSink(rdb, eventRedisStream, 0))
+		case "http":
+			if eventHTTPURL == "" {
+				log.Fatalf("EVENT_HTTP_URL is required for the http event sink")
+			}
+			eventSinks = append(eventSinks, events.NewHTTPSink(events.HTTPOptions{
+				URL:    eventHTTPURL,
+				Secret: eventHTTPSecret,
+			}))
+		default:
+			log.Fatalf("invalid EVENT_SINKS entry: %q", name)
+		}
+	}
+	// 未配置 sink 时不写 outbox，避免事件在 Redis 中无限堆积；多实例部署时各实例的 EVENT_SINKS 需保持一致
+	if len(eventSinks) > 0 {
+		go events.NewRelay(repo, eventSinks, events.Options{}).Run(ctx)
+	}
+
+	serviceOpts := []service.Option{
+		service.WithClickRecorder(clickRepo),
+		service.WithWebhooks(webhookRepo),
+		service.WithLiveHub(liveHub),
+		service.WithLiveStreamToken(os.Getenv("LIVE_STREAM_TOKEN")),
+		service.WithBotClassifier(botClassifier),
+		service.WithVisitorSalt(os.Getenv("VISITOR_SALT")),
+		service.WithPrivacyPolicy(privacy),
+		service.WithAttributionWindow(time.Duration(attributionWindowDays) * 24 * time.Hour),
+		service.WithClickPipeline(analytics.Options{
+			BufferSize: clickBufferSize,
+			Workers:    clickWorkers,
+		}),
+	}
+	if fallbackURL != "" {
+		serviceOpts = append(serviceOpts, service.WithFallbackURL(fallbackURL))
+	}
+	if len(eventSinks) > 0 {
+		serviceOpts = append(serviceOpts, service.WithDomainEvents())
+	}
+	if metadataFetchEnabled {
+		serviceOpts = append(serviceOpts, service.WithMetadataFetcher(metadata.NewFetcher(metadata.Options{})))
+	}
+	linkService := service.NewLinkService(repo, baseURL, serviceOpts...)
+	go linkService.RunClickRetention(ctx)
+	go linkService.RunExpiryNotifier(ctx)
 
 	// 初始化 Handler
-	linkHandler := handler.NewLinkHandler(linkService)
+	var handlerOpts []handler.HandlerOption
+	if countryHeader != "" {
+		handlerOpts = append(handlerOpts, handler.WithCountryHeader(countryHeader))
+	}
+	linkHandler := handler.NewLinkHandler(linkService, handlerOpts...)
 
 	// 设置 Gin 模式
 	if os.Getenv("GIN_MODE") == "" {
@@ -57,13 +298,16 @@ func main() {
 
 	// 创建路由
 	r := gin.Default()
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// 添加 CORS 中间件（允许前端访问）
 	r.Use(func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Link-Password")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -83,15 +327,65 @@ func main() {
 	{
 		api.POST("/shorten", linkHandler.Shorten)
 		api.GET("/links/:code", linkHandler.GetLinkInfo)
+		api.PATCH("/links/:code", linkHandler.UpdateLink)
+		api.DELETE("/links/:code", linkHandler.DeleteLink)
+		api.GET("/links/:code/stats", linkHandler.GetClickStats)
+		api.GET("/links/:code/breakdown", linkHandler.GetBreakdown)
+		api.GET("/links/:code/live", linkHandler.StreamClicks)
+		api.GET("/links/:code/export/clicks", linkHandler.ExportClicks)
+		api.GET("/links/:code/export/stats", linkHandler.ExportStats)
+		api.GET("/live", linkHandler.StreamAllClicks)
+		api.GET("/leaderboard", linkHandler.GetLeaderboard)
+		api.POST("/links/:code/disable", linkHandler.SetDisabled(true))
+		api.POST("/links/:code/enable", linkHandler.SetDisabled(false))
+		api.POST("/links/:code/webhooks", linkHandler.CreateWebhook)
+		api.GET("/links/:code/webhooks", linkHandler.ListWebhooks)
+		api.DELETE("/links/:code/webhooks/:id", linkHandler.DeleteWebhook)
+		api.GET("/links/:code/webhooks/:id/deliveries", linkHandler.ListWebhookDeliveries)
+		api.GET("/reports/broken", linkHandler.ListBrokenLinks)
+		api.GET("/track/pixel.gif", linkHandler.TrackPixel)
+		api.POST("/track/conversion", linkHandler.TrackConversion)
 	}
 
 	// 短链接重定向路由（必须在最后，避免与其他路由冲突）
 	r.GET("/:code", linkHandler.Redirect)
+	r.POST("/:code", linkHandler.VerifyPassword)
+
+	// 启动服务器，收到 SIGINT/SIGTERM 后优雅退出（等待进行中的请求与点击事件处理完毕）
+	srv := &http.Server{Addr: ":" + port, Handler: r}
+	// Shutdown 不会中断长连接，关闭实时订阅让 SSE 连接自行结束
+	srv.RegisterOnShutdown(liveCancel)
+	go func() {
+		log.Printf("Server starting on port %s", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("failed to start server: %v", err)
+		}
+	}()
 
-	// 启动服务器
-	log.Printf("Server starting on port %s", port)
-	if err := r.Run(":" + port); err != nil {
-		log.Fatalf("failed to start server: %v", err)
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := botClassifier.Reload(); err != nil {
+				log.Printf("failed to reload bot rules: %v", err)
+				continue
+			}
+			log.Println("Bot rules reloaded")
+		}
+	}()
+
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-sigCtx.Done()
+	log.Println("Shutting down server")
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("failed to shut down server: %v", err)
+	}
+	if err := linkService.Close(shutdownCtx); err != nil {
+		log.Printf("failed to close link service: %v", err)
 	}
 }
 
@@ -126,3 +420,14 @@ func initRedis(addr, password string, db int) (*redisv9.Client, error) {
 
 	return rdb, nil
 }
+
+// splitList 解析逗号分隔的配置项，忽略空白项
+func splitList(v string) []string {
+	var items []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,115 @@
+package analytics
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"url-shortener/backend/internal/model"
+)
+
+// subscriberBuffer 每个订阅者的缓冲长度，消费过慢时丢弃新事件
+const subscriberBuffer = 64
+
+// ClickSource 提供跨实例的实时点击事件（如 Redis pub/sub）
+type ClickSource interface {
+	// SubscribeClicks 订阅所有短码的点击事件，ctx 结束时关闭返回的通道
+	SubscribeClicks(ctx context.Context) (<-chan *model.ClickEvent, error)
+}
+
+// Hub 实时点击事件的本地分发中心：每个实例只向 ClickSource 订阅一次，
+// 再按短码分发给本实例上的 SSE 连接
+type Hub struct {
+	source ClickSource
+
+	mu     sync.Mutex
+	subs   map[*subscription]struct{}
+	closed bool
+}
+
+type subscription struct {
+	// code 为空表示订阅所有短码
+	code   string
+	events chan *model.ClickEvent
+}
+
+func NewHub(source ClickSource) *Hub {
+	return &Hub{
+		source: source,
+		subs:   make(map[*subscription]struct{}),
+	}
+}
+
+// Subscribe 订阅某个短码（code 为空时为全部短码）的点击事件，
+// 返回的取消函数用于退订；Hub 关闭后通道会被关闭
+func (h *Hub) Subscribe(code string) (<-chan *model.ClickEvent, func()) {
+	sub := &subscription{code: code, events: make(chan *model.ClickEvent, subscriberBuffer)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(sub.events)
+		return sub.events, func() {}
+	}
+	h.subs[sub] = struct{}{}
+
+	return sub.events, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := h.subs[sub]; ok {
+			delete(h.subs, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// Run 持续从 ClickSource 接收事件并分发，订阅中断时按退避重试；ctx 结束时关闭所有订阅
+func (h *Hub) Run(ctx context.Context) {
+	defer h.close()
+
+	backoff := time.Second
+	for ctx.Err() == nil {
+		events, err := h.source.SubscribeClicks(ctx)
+		if err != nil {
+			log.Printf("failed to subscribe to live clicks: %v", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff < 30*time.Second {
+				backoff *= 2
+			}
+			continue
+		}
+		backoff = time.Second
+		for event := range events {
+			h.dispatch(event)
+		}
+	}
+}
+
+func (h *Hub) dispatch(event *model.ClickEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		if sub.code != "" && sub.code != event.Code {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+func (h *Hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		close(sub.events)
+	}
+}
@@ -0,0 +1,121 @@
+package analytics
+
+import (
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"url-shortener/backend/internal/model"
+)
+
+const (
+	defaultBufferSize    = 10000
+	defaultWorkers       = 4
+	defaultHandleTimeout = 5 * time.Second
+)
+
+// Handler 处理一条点击事件
+type Handler func(ctx context.Context, event *model.ClickEvent)
+
+// Options 点击管道配置，零值字段使用默认值
+type Options struct {
+	// BufferSize 缓冲队列长度，队列满时丢弃新事件
+	BufferSize int
+	// Workers 并发处理事件的 worker 数
+	Workers int
+	// HandleTimeout 单个事件的处理超时
+	HandleTimeout time.Duration
+}
+
+// Pipeline 有界的异步点击事件管道：重定向请求只负责入队，
+// 由固定数量的 worker 调用 Handler 写入存储，避免每个请求启动一个 goroutine
+type Pipeline struct {
+	events  chan *model.ClickEvent
+	handler Handler
+	timeout time.Duration
+	wg      sync.WaitGroup
+	dropped atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewPipeline(handler Handler, opts Options) *Pipeline {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = defaultBufferSize
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.HandleTimeout <= 0 {
+		opts.HandleTimeout = defaultHandleTimeout
+	}
+
+	p := &Pipeline{
+		events:  make(chan *model.ClickEvent, opts.BufferSize),
+		handler: handler,
+		timeout: opts.HandleTimeout,
+	}
+	for i := 0; i < opts.Workers; i++ {
+		p.wg.Add(1)
+		go p.worker()
+	}
+	return p
+}
+
+// Submit 非阻塞地提交事件；队列已满或管道已关闭时丢弃并返回 false
+func (p *Pipeline) Submit(event *model.ClickEvent) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
+	}
+
+	select {
+	case p.events <- event:
+		return true
+	default:
+		if n := p.dropped.Add(1); n == 1 || n%1000 == 0 {
+			log.Printf("click pipeline is full, %d events dropped so far", n)
+		}
+		return false
+	}
+}
+
+// Dropped 返回因队列已满而丢弃的事件数
+func (p *Pipeline) Dropped() int64 {
+	return p.dropped.Load()
+}
+
+// Close 停止接收新事件，并等待队列中剩余事件处理完毕（或 ctx 结束）
+func (p *Pipeline) Close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.events)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p *Pipeline) worker() {
+	defer p.wg.Done()
+	for event := range p.events {
+		ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+		p.handler(ctx, event)
+		cancel()
+	}
+}
@@ -0,0 +1,165 @@
+package botdetect
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"sync"
+)
+
+// genericBotPattern 通用的机器人特征，可在规则文件中用 !bot 移除
+const genericBotPattern = "bot"
+
+// defaultPatterns 内置的爬虫、链接预览、监控与安全扫描工具 User-Agent 特征（小写子串）
+var defaultPatterns = []string{
+	// 通用爬虫（"bot" 只按词尾匹配，见 containsBotToken）
+	genericBotPattern,
+	"crawler",
+	"spider",
+	"slurp",
+	"archiver",
+	// 链接预览（不含 "bot" 或通用规则匹配不到的部分，如 "Slackbot 1.0"）
+	"slackbot",
+	"facebookexternalhit",
+	"facebookcatalog",
+	"slack-imgproxy",
+	"whatsapp",
+	"skypeuripreview",
+	"vkshare",
+	"embedly",
+	"iframely",
+	"mattermost",
+	"microsoft teams",
+	// 可用性监控
+	"pingdom",
+	"uptimerobot",
+	"statuscake",
+	"site24x7",
+	"newrelicpinger",
+	"datadog",
+	"urlshortenerhealthcheck",
+	// 安全扫描
+	"nmap",
+	"nikto",
+	"sqlmap",
+	"masscan",
+	"zgrab",
+	"nuclei",
+	"wpscan",
+	"acunetix",
+	"netsparker",
+	"qualys",
+	// 命令行工具与 HTTP 库
+	"curl/",
+	"wget/",
+	"python-requests",
+	"python-urllib",
+	"aiohttp",
+	"go-http-client",
+	"java/",
+	"okhttp",
+	"libwww-perl",
+	"apache-httpclient",
+	"headlesschrome",
+	"phantomjs",
+}
+
+// Classifier 根据 User-Agent 特征判断请求是否来自机器人。
+// 规则由内置特征加上可选的规则文件组成，规则文件可在运行时重新加载
+type Classifier struct {
+	path string
+
+	mu       sync.RWMutex
+	patterns []string
+}
+
+// NewClassifier 创建分类器；path 为空时只使用内置特征
+func NewClassifier(path string) (*Classifier, error) {
+	c := &Classifier{path: path, patterns: defaultPatterns}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// IsBot 判断 User-Agent 是否为机器人；空 User-Agent 视为机器人（浏览器总会发送）
+func (c *Classifier) IsBot(userAgent string) bool {
+	ua := strings.ToLower(strings.TrimSpace(userAgent))
+	if ua == "" {
+		return true
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, pattern := range c.patterns {
+		if pattern == genericBotPattern {
+			if containsBotToken(ua) {
+				return true
+			}
+			continue
+		}
+		if strings.Contains(ua, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsBotToken 判断 "bot" 是否作为产品名词尾出现（googlebot/2.1、petalbot;、duckduckbot-https），
+// 不匹配 Cubot 手机型号（CUBOT_X30、Cubot X30）、Botim 等只是包含该子串的浏览器与应用
+func containsBotToken(ua string) bool {
+	for i := 0; ; {
+		j := strings.Index(ua[i:], genericBotPattern)
+		if j < 0 {
+			return false
+		}
+		end := i + j + len(genericBotPattern)
+		if end == len(ua) || strings.IndexByte("/;)-@", ua[end]) >= 0 {
+			return true
+		}
+		i = end
+	}
+}
+
+// Reload 重新读取规则文件。文件每行一个特征（不区分大小写的子串），# 开头为注释，
+// ! 开头表示从内置特征中移除该项；读取失败时保留原有规则
+func (c *Classifier) Reload() error {
+	if c.path == "" {
+		return nil
+	}
+
+	f, err := os.Open(c.path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	removed := make(map[string]bool)
+	var extra []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		switch {
+		case line == "", strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "!"):
+			removed[strings.TrimSpace(line[1:])] = true
+		default:
+			extra = append(extra, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	patterns := make([]string, 0, len(defaultPatterns)+len(extra))
+	for _, pattern := range append(defaultPatterns[:len(defaultPatterns):len(defaultPatterns)], extra...) {
+		if !removed[pattern] {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	c.mu.Lock()
+	c.patterns = patterns
+	c.mu.Unlock()
+	return nil
+}
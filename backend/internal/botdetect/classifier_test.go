@@ -0,0 +1,121 @@
+package botdetect
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClassifierDefaults(t *testing.T) {
+	c, err := NewClassifier("")
+	if err != nil {
+		t.Fatalf("NewClassifier: %v", err)
+	}
+	tests := []struct {
+		ua   string
+		want bool
+	}{
+		{"", true},
+		{"   ", true},
+		{"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", true},
+		{"facebookexternalhit/1.1", true},
+		{"curl/8.4.0", true},
+		{"Go-http-client/1.1", true},
+		{"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0", false},
+		{"Slackbot 1.0 (+https://api.slack.com/robots)", true},
+		{"TelegramBot (like TwitterBot)", true},
+		{"DuckDuckBot-Https/1.1; (+https://duckduckgo.com/duckduckbot)", true},
+		{"Mozilla/5.0 (compatible; PetalBot;+https://webmaster.petalsearch.com/site/petalbot)", true},
+		{"custom-monitor-bot", true},
+		{"Mozilla/5.0 (Linux; Android 10; CUBOT_X30) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36", false},
+		{"Mozilla/5.0 (Linux; Android 11; Cubot X30 Build/RP1A) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36", false},
+		{"Botim/4.2 (iPhone; iOS 17.2)", false},
+	}
+	for _, tt := range tests {
+		if got := c.IsBot(tt.ua); got != tt.want {
+			t.Errorf("IsBot(%q) = %v, want %v", tt.ua, got, tt.want)
+		}
+	}
+}
+
+func TestClassifierRemoveGenericPattern(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bots.txt")
+	writeRules(t, path, "!bot\n")
+
+	c, err := NewClassifier(path)
+	if err != nil {
+		t.Fatalf("NewClassifier: %v", err)
+	}
+	if c.IsBot("Googlebot/2.1") {
+		t.Error("generic bot pattern still applied after !bot")
+	}
+	if !c.IsBot("Slackbot 1.0") {
+		t.Error("specific patterns removed together with !bot")
+	}
+}
+
+func TestClassifierRulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bots.txt")
+	writeRules(t, path, "# 自定义规则\n\nMyMonitor\n!curl/\n")
+
+	c, err := NewClassifier(path)
+	if err != nil {
+		t.Fatalf("NewClassifier: %v", err)
+	}
+	tests := []struct {
+		ua   string
+		want bool
+	}{
+		{"mymonitor/2.0", true},
+		{"curl/8.4.0", false},
+		{"wget/1.21", true},
+		{"# 自定义规则", false},
+	}
+	for _, tt := range tests {
+		if got := c.IsBot(tt.ua); got != tt.want {
+			t.Errorf("IsBot(%q) = %v, want %v", tt.ua, got, tt.want)
+		}
+	}
+
+	writeRules(t, path, "!wget/\n")
+	if err := c.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	reloaded := []struct {
+		ua   string
+		want bool
+	}{
+		{"mymonitor/2.0", false},
+		{"curl/8.4.0", true},
+		{"wget/1.21", false},
+	}
+	for _, tt := range reloaded {
+		if got := c.IsBot(tt.ua); got != tt.want {
+			t.Errorf("after reload IsBot(%q) = %v, want %v", tt.ua, got, tt.want)
+		}
+	}
+
+	// 读取失败时保留原有规则
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reload(); err == nil {
+		t.Fatal("Reload of missing file returned nil error")
+	}
+	if c.IsBot("wget/1.21") {
+		t.Error("rules changed after failed reload")
+	}
+}
+
+func TestNewClassifierMissingFile(t *testing.T) {
+	if _, err := NewClassifier(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Fatal("NewClassifier with missing file returned nil error")
+	}
+}
+
+func writeRules(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,154 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"url-shortener/backend/internal/model"
+)
+
+const (
+	defaultFilePrefix   = "events"
+	defaultFileMaxBytes = 100 << 20
+	defaultFileMaxFiles = 10
+)
+
+// FileOptions 文件 sink 配置，零值字段使用默认值
+type FileOptions struct {
+	// Dir 事件文件所在目录（不存在时自动创建）
+	Dir string
+	// Prefix 文件名前缀：当前文件为 {prefix}.ndjson，轮转后为 {prefix}-{UTC 时间}.ndjson
+	Prefix string
+	// MaxBytes 当前文件超过该大小时轮转
+	MaxBytes int64
+	// MaxFiles 最多保留的已轮转文件数，超出时删除最旧的文件
+	MaxFiles int
+}
+
+// FileSink 把事件以 NDJSON 追加写入本地文件，按大小轮转。
+// 每批事件写入后调用 fsync，确保确认 outbox 之前事件已落盘
+type FileSink struct {
+	mu   sync.Mutex
+	opts FileOptions
+	file *os.File
+	size int64
+}
+
+func NewFileSink(opts FileOptions) (*FileSink, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("event file directory is required")
+	}
+	if opts.Prefix == "" {
+		opts.Prefix = defaultFilePrefix
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = defaultFileMaxBytes
+	}
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = defaultFileMaxFiles
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	s := &FileSink{opts: opts}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) Name() string {
+	return "file"
+}
+
+func (s *FileSink) Publish(_ context.Context, events []*model.DomainEvent) error {
+	data, err := encodeNDJSON(events)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return fmt.Errorf("event file is closed")
+	}
+	if s.size > 0 && s.size+int64(len(data)) > s.opts.MaxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.file.Write(data)
+	s.size += int64(n)
+	if err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Close 关闭当前文件
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+func (s *FileSink) currentPath() string {
+	return filepath.Join(s.opts.Dir, s.opts.Prefix+".ndjson")
+}
+
+// open 以追加方式打开当前文件，重启后继续写入上次的文件
+func (s *FileSink) open() error {
+	file, err := os.OpenFile(s.currentPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	s.file = file
+	s.size = info.Size()
+	return nil
+}
+
+// rotate 将当前文件改名为带时间戳的文件，打开新文件并清理超出数量的旧文件
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.file = nil
+
+	// 时间戳精确到纳秒，文件名按字典序即按时间排序
+	rotated := filepath.Join(s.opts.Dir, fmt.Sprintf("%s-%s.ndjson", s.opts.Prefix, time.Now().UTC().Format("20060102T150405.000000000")))
+	if err := os.Rename(s.currentPath(), rotated); err != nil {
+		return err
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	old, err := filepath.Glob(filepath.Join(s.opts.Dir, s.opts.Prefix+"-*.ndjson"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(old)
+	for len(old) > s.opts.MaxFiles {
+		if err := os.Remove(old[0]); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		old = old[1:]
+	}
+	return nil
+}
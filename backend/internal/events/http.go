@@ -0,0 +1,76 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/webhook"
+)
+
+const defaultHTTPTimeout = 10 * time.Second
+
+// HTTPOptions HTTP sink 配置
+type HTTPOptions struct {
+	URL string
+	// Secret 非空时按 webhook 相同的方式签名（X-Webhook-Timestamp / X-Webhook-Signature）
+	Secret  string
+	Timeout time.Duration
+}
+
+// HTTPSink 把每批事件以 NDJSON 请求体 POST 到固定地址，返回 2xx 视为成功。
+// 地址由运维配置（通常是内网服务），因此不做 SSRF 限制
+type HTTPSink struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewHTTPSink(opts HTTPOptions) *HTTPSink {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultHTTPTimeout
+	}
+	return &HTTPSink{
+		url:    opts.URL,
+		secret: opts.Secret,
+		client: &http.Client{Timeout: opts.Timeout},
+	}
+}
+
+func (s *HTTPSink) Name() string {
+	return "http"
+}
+
+func (s *HTTPSink) Publish(ctx context.Context, events []*model.DomainEvent) error {
+	body, err := encodeNDJSON(events)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.secret != "" {
+		timestamp := time.Now().Unix()
+		req.Header.Set(webhook.TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(webhook.SignatureHeader, webhook.Sign(s.secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"strconv"
+
+	redisv9 "github.com/redis/go-redis/v9"
+
+	"url-shortener/backend/internal/model"
+)
+
+const defaultStreamMaxLen = 1000000
+
+// RedisStreamSink 把事件追加到 Redis Stream，供下游用消费组读取
+//
+// fields: id (outbox 事件 ID), type, code, ts (Unix 毫秒), data (JSON)
+type RedisStreamSink struct {
+	rdb    *redisv9.Client
+	stream string
+	maxLen int64
+}
+
+// NewRedisStreamSink 创建写入 stream 的 sink，maxLen <= 0 时最多保留 100 万条（近似裁剪）
+func NewRedisStreamSink(rdb *redisv9.Client, stream string, maxLen int64) *RedisStreamSink {
+	if maxLen <= 0 {
+		maxLen = defaultStreamMaxLen
+	}
+	return &RedisStreamSink{rdb: rdb, stream: stream, maxLen: maxLen}
+}
+
+func (s *RedisStreamSink) Name() string {
+	return "redis"
+}
+
+func (s *RedisStreamSink) Publish(ctx context.Context, events []*model.DomainEvent) error {
+	_, err := s.rdb.TxPipelined(ctx, func(pipe redisv9.Pipeliner) error {
+		for _, event := range events {
+			pipe.XAdd(ctx, &redisv9.XAddArgs{
+				Stream: s.stream,
+				MaxLen: s.maxLen,
+				Approx: true,
+				Values: []any{
+					"id", event.ID,
+					"type", event.Type,
+					"code", event.Code,
+					"ts", strconv.FormatInt(event.OccurredAt.UnixMilli(), 10),
+					"data", string(event.Data),
+				},
+			})
+		}
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,156 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"url-shortener/backend/internal/storage"
+)
+
+const (
+	defaultBatchSize = 100
+	// readBlock 没有新事件时单次读取最多等待的时长
+	readBlock = 5 * time.Second
+	// minRetryDelay / maxRetryDelay 读取或投递失败后的重试间隔（指数退避）
+	minRetryDelay = time.Second
+	maxRetryDelay = time.Minute
+	// trimInterval 删除所有 sink 都已确认的事件的间隔
+	trimInterval = time.Minute
+)
+
+// Options Relay 配置，零值字段使用默认值
+type Options struct {
+	// BatchSize 每次从 outbox 读取并投递的事件数
+	BatchSize int
+	// Consumer 当前实例在消费组中的名称，默认为 主机名-进程号
+	Consumer string
+}
+
+// Relay 把 outbox 中的领域事件投递给各个 sink：每个 sink 使用独立的消费组，按顺序逐批投递，
+// 投递成功后才确认。进程在投递与确认之间崩溃时，未确认的事件会在超时后被重新读取并投递
+type Relay struct {
+	outbox    storage.Outbox
+	sinks     []Sink
+	batchSize int
+	consumer  string
+}
+
+func NewRelay(outbox storage.Outbox, sinks []Sink, opts Options) *Relay {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.Consumer == "" {
+		host, _ := os.Hostname()
+		opts.Consumer = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	return &Relay{
+		outbox:    outbox,
+		sinks:     sinks,
+		batchSize: opts.BatchSize,
+		consumer:  opts.Consumer,
+	}
+}
+
+// Run 为每个 sink 启动一个投递循环，并定期清理已投递的事件，直到 ctx 取消
+func (r *Relay) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, sink := range r.sinks {
+		wg.Add(1)
+		go func(sink Sink) {
+			defer wg.Done()
+			r.runSink(ctx, sink)
+		}(sink)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.runTrim(ctx)
+	}()
+	wg.Wait()
+}
+
+// runTrim 定期删除所有 sink 都已确认的事件；未确认的事件（如 sink 长时间不可用）一直保留
+func (r *Relay) runTrim(ctx context.Context) {
+	groups := make([]string, len(r.sinks))
+	for i, sink := range r.sinks {
+		groups[i] = groupName(sink)
+	}
+
+	ticker := time.NewTicker(trimInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := r.outbox.TrimOutbox(ctx, groups); err != nil && ctx.Err() == nil {
+			log.Printf("failed to trim outbox: %v", err)
+		}
+	}
+}
+
+// groupName 返回 sink 在 outbox 中的消费组名
+func groupName(sink Sink) string {
+	return "sink:" + sink.Name()
+}
+
+// runSink 循环读取一批事件并投递，投递失败时重试同一批，保证同一 sink 内的顺序
+func (r *Relay) runSink(ctx context.Context, sink Sink) {
+	group := groupName(sink)
+	delay := minRetryDelay
+	for ctx.Err() == nil {
+		events, err := r.outbox.ReadOutbox(ctx, group, r.consumer, r.batchSize, readBlock)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("failed to read outbox for sink %s: %v", sink.Name(), err)
+			}
+			delay = sleep(ctx, delay)
+			continue
+		}
+		if len(events) == 0 {
+			continue
+		}
+
+		for {
+			err := sink.Publish(ctx, events)
+			if err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("failed to publish %d events to sink %s: %v", len(events), sink.Name(), err)
+			delay = sleep(ctx, delay)
+		}
+		delay = minRetryDelay
+
+		ids := make([]string, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		// 确认失败时事件会被重新投递（至少一次），不影响后续投递
+		if err := r.outbox.AckOutbox(context.WithoutCancel(ctx), group, ids...); err != nil {
+			log.Printf("failed to ack outbox for sink %s: %v", sink.Name(), err)
+		}
+	}
+}
+
+// sleep 等待 delay（ctx 取消时提前返回），返回下一次的等待时长
+func sleep(ctx context.Context, delay time.Duration) time.Duration {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+	delay *= 2
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,30 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"url-shortener/backend/internal/model"
+)
+
+// Sink 领域事件的下游。Publish 返回 nil 表示这批事件已持久化到下游，之后才会在 outbox 中确认；
+// 返回错误时 Relay 会按原顺序重试同一批事件，因此下游可能收到重复事件，需按事件 ID 去重
+type Sink interface {
+	// Name 返回 sink 名称，同时用作 outbox 消费组名，每个 sink 独立记录投递进度
+	Name() string
+	Publish(ctx context.Context, events []*model.DomainEvent) error
+}
+
+// encodeNDJSON 将事件编码为每行一个 JSON 对象
+func encodeNDJSON(events []*model.DomainEvent) ([]byte, error) {
+	var buf []byte
+	for _, event := range events {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+	return buf, nil
+}
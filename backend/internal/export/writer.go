@@ -0,0 +1,130 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"url-shortener/backend/internal/model"
+)
+
+// 导出格式
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// ErrInvalidFormat 不支持的导出格式
+var ErrInvalidFormat = errors.New("format must be csv or ndjson")
+
+var (
+	clickHeader  = []string{"id", "code", "timestamp", "variant", "referrer_host", "ua_class", "browser", "country", "ip", "bot"}
+	bucketHeader = []string{"code", "interval", "start", "clicks", "bot_clicks", "uniques", "conversions"}
+)
+
+// Writer 逐条写出点击事件或统计桶，CSV 在第一条记录前写表头；
+// 不缓存记录，调用方按需 Flush 以便分块发送
+type Writer struct {
+	csv    *csv.Writer
+	json   *json.Encoder
+	header bool
+}
+
+func NewWriter(out io.Writer, format string) (*Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &Writer{csv: csv.NewWriter(out)}, nil
+	case FormatNDJSON:
+		return &Writer{json: json.NewEncoder(out)}, nil
+	}
+	return nil, ErrInvalidFormat
+}
+
+// ContentType 返回导出格式对应的 MIME 类型
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// WriteClick 写出一条原始点击事件
+func (w *Writer) WriteClick(event *model.ClickEvent) error {
+	if w.json != nil {
+		return w.json.Encode(event)
+	}
+	if err := w.writeHeader(clickHeader); err != nil {
+		return err
+	}
+	return w.csv.Write([]string{
+		event.ID,
+		event.Code,
+		event.Timestamp.UTC().Format(time.RFC3339Nano),
+		event.Variant,
+		event.ReferrerHost,
+		event.UserAgentClass,
+		event.Browser,
+		event.Country,
+		event.IP,
+		strconv.FormatBool(event.Bot),
+	})
+}
+
+// bucketRecord NDJSON 格式的统计桶记录
+type bucketRecord struct {
+	Code     string `json:"code"`
+	Interval string `json:"interval"`
+	model.StatsBucket
+}
+
+// WriteBucket 写出一个统计桶
+func (w *Writer) WriteBucket(code, interval string, bucket model.StatsBucket) error {
+	if w.json != nil {
+		return w.json.Encode(bucketRecord{Code: code, Interval: interval, StatsBucket: bucket})
+	}
+	if err := w.writeHeader(bucketHeader); err != nil {
+		return err
+	}
+	uniques := ""
+	if bucket.Uniques != nil {
+		uniques = strconv.FormatInt(*bucket.Uniques, 10)
+	}
+	return w.csv.Write([]string{
+		code,
+		interval,
+		bucket.Start.UTC().Format(time.RFC3339),
+		strconv.FormatInt(bucket.Clicks, 10),
+		strconv.FormatInt(bucket.BotClicks, 10),
+		uniques,
+		strconv.FormatInt(bucket.Conversions, 10),
+	})
+}
+
+// WriteClickHeader / WriteBucketHeader 在没有任何记录时写出 CSV 表头（NDJSON 无需表头）
+func (w *Writer) WriteClickHeader() error {
+	return w.writeHeader(clickHeader)
+}
+
+func (w *Writer) WriteBucketHeader() error {
+	return w.writeHeader(bucketHeader)
+}
+
+// Flush 将缓冲的 CSV 数据写入底层 io.Writer
+func (w *Writer) Flush() error {
+	if w.csv == nil {
+		return nil
+	}
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func (w *Writer) writeHeader(header []string) error {
+	if w.csv == nil || w.header {
+		return nil
+	}
+	w.header = true
+	return w.csv.Write(header)
+}
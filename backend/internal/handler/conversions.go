@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/backend/internal/service"
+)
+
+// attributionCookieName 保存归因令牌的 cookie 名
+const attributionCookieName = "sl_attr"
+
+// transparentGIF 1x1 透明 GIF
+var transparentGIF = []byte{
+	0x47, 0x49, 0x46, 0x38, 0x39, 0x61, 0x01, 0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0xff, 0xff, 0xff, 0x21, 0xf9, 0x04, 0x01, 0x00, 0x00, 0x00, 0x00, 0x2c, 0x00, 0x00, 0x00, 0x00,
+	0x01, 0x00, 0x01, 0x00, 0x00, 0x02, 0x02, 0x44, 0x01, 0x00, 0x3b,
+}
+
+// TrackPixel 转化像素：目标站点在转化页面嵌入该图片即可上报转化，
+// 令牌取自 token 参数或 sl_attr cookie。无论上报是否成功都返回图片，避免页面出现破图
+// GET /api/v1/track/pixel.gif?token=&goal=&value=
+func (h *LinkHandler) TrackPixel(c *gin.Context) {
+	var req service.ConversionRequest
+	if err := c.ShouldBindQuery(&req); err == nil {
+		if req.Token == "" {
+			req.Token, _ = c.Cookie(attributionCookieName)
+		}
+		if _, err := h.service.TrackConversion(c.Request.Context(), &req); err != nil {
+			var svcErr *service.ServiceError
+			if !errors.As(err, &svcErr) || svcErr.Type == "internal_error" {
+				log.Printf("failed to track conversion pixel: %v", err)
+			}
+		}
+	}
+
+	c.Header("Cache-Control", "no-store, max-age=0")
+	c.Data(http.StatusOK, "image/gif", transparentGIF)
+}
+
+// TrackConversion 服务端上报转化，token 省略时使用 sl_attr cookie
+// POST /api/v1/track/conversion
+func (h *LinkHandler) TrackConversion(c *gin.Context) {
+	var req service.ConversionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+	if req.Token == "" {
+		req.Token, _ = c.Cookie(attributionCookieName)
+	}
+
+	resp, err := h.service.TrackConversion(c.Request.Context(), &req)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/backend/internal/export"
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/service"
+)
+
+// exportFlushEvery 每写出多少条记录向客户端发送一次数据块
+const exportFlushEvery = 500
+
+// ExportClicks 以 CSV 或 NDJSON 流式导出原始点击事件，受密码保护的链接需通过 X-Link-Password 头提供密码
+// GET /api/v1/links/{code}/export/clicks?from=&to=&format=csv|ndjson
+func (h *LinkHandler) ExportClicks(c *gin.Context) {
+	req, w, ok := bindExport(c)
+	if !ok {
+		return
+	}
+	code := c.Param("code")
+	if err := h.service.AuthorizeClickData(c.Request.Context(), code, c.GetHeader("X-Link-Password")); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	// 响应头在写出第一条记录前才发送，此前的错误仍可返回 JSON
+	started := false
+	written := 0
+	err := h.service.ExportClicks(c.Request.Context(), code, req, func(event *model.ClickEvent) error {
+		if !started {
+			startExport(c, req.Format, code+"-clicks")
+			started = true
+		}
+		if err := w.WriteClick(event); err != nil {
+			return err
+		}
+		written++
+		if written%exportFlushEvery == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+		return nil
+	})
+	if err != nil && !started {
+		writeServiceError(c, err)
+		return
+	}
+	if err != nil {
+		// 已开始发送数据，只能中断响应
+		log.Printf("failed to export clicks of %s: %v", code, err)
+		return
+	}
+
+	if !started {
+		startExport(c, req.Format, code+"-clicks")
+		_ = w.WriteClickHeader()
+	}
+	_ = w.Flush()
+}
+
+// ExportStats 以 CSV 或 NDJSON 导出按小时或按天聚合的点击统计，受密码保护的链接需通过 X-Link-Password 头提供密码
+// GET /api/v1/links/{code}/export/stats?from=&to=&interval=hour|day&format=csv|ndjson
+func (h *LinkHandler) ExportStats(c *gin.Context) {
+	req, w, ok := bindExport(c)
+	if !ok {
+		return
+	}
+	code := c.Param("code")
+	if err := h.service.AuthorizeClickData(c.Request.Context(), code, c.GetHeader("X-Link-Password")); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	stats, err := h.service.ExportStats(c.Request.Context(), code, req)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	startExport(c, req.Format, code+"-stats")
+	_ = w.WriteBucketHeader()
+	for _, bucket := range stats.Buckets {
+		if err := w.WriteBucket(stats.Code, stats.Interval, bucket); err != nil {
+			log.Printf("failed to export stats of %s: %v", code, err)
+			return
+		}
+	}
+	_ = w.Flush()
+}
+
+// bindExport 解析导出参数并创建对应格式的 Writer（默认 CSV），参数错误时直接返回 400
+func bindExport(c *gin.Context) (*service.ExportRequest, *export.Writer, bool) {
+	var req service.ExportRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return nil, nil, false
+	}
+	if req.Format == "" {
+		req.Format = export.FormatCSV
+	}
+
+	w, err := export.NewWriter(c.Writer, req.Format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return nil, nil, false
+	}
+	return &req, w, true
+}
+
+// startExport 写出导出响应头（分块传输，不设置 Content-Length）
+func startExport(c *gin.Context, format, name string) {
+	c.Header("Content-Type", export.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+}
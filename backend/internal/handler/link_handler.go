@@ -1,19 +1,50 @@
 package handler
 
 import (
+	"html/template"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"url-shortener/backend/internal/model"
 	"url-shortener/backend/internal/service"
 )
 
+const (
+	// variantCookieMaxAge 粘性分流 cookie 有效期（30 天）
+	variantCookieMaxAge = 30 * 24 * 60 * 60
+	// sseHeartbeatInterval 实时点击流的心跳间隔
+	sseHeartbeatInterval = 15 * time.Second
+)
+
 type LinkHandler struct {
 	service *service.LinkService
+	// countryHeader 为 CDN/反向代理写入访问者国家代码的请求头（如 CF-IPCountry），为空则不统计国家
+	countryHeader string
 }
 
-func NewLinkHandler(svc *service.LinkService) *LinkHandler {
-	return &LinkHandler{service: svc}
+// HandlerOption 配置 LinkHandler 的可选项
+type HandlerOption func(*LinkHandler)
+
+// WithCountryHeader 设置读取访问者国家代码的请求头，只应在该头由可信代理写入时启用
+func WithCountryHeader(header string) HandlerOption {
+	return func(h *LinkHandler) {
+		h.countryHeader = header
+	}
+}
+
+func NewLinkHandler(svc *service.LinkService, opts ...HandlerOption) *LinkHandler {
+	h := &LinkHandler{service: svc}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
 }
 
 // Shorten 创建短链接
@@ -30,36 +61,22 @@ func (h *LinkHandler) Shorten(c *gin.Context) {
 
 	resp, err := h.service.CreateShortLink(c.Request.Context(), &req)
 	if err != nil {
-		if svcErr, ok := err.(*service.ServiceError); ok {
-			statusCode := http.StatusInternalServerError
-			switch svcErr.Type {
-			case "invalid_request":
-				statusCode = http.StatusBadRequest
-			case "conflict":
-				statusCode = http.StatusConflict
-			case "not_found":
-				statusCode = http.StatusNotFound
-			}
-			c.JSON(statusCode, gin.H{
-				"error":   svcErr.Type,
-				"message": svcErr.Message,
-			})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "internal_error",
-			"message": "an unexpected error occurred",
-		})
+		writeServiceError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, resp)
 }
 
-// Redirect 短链接重定向
+// Redirect 短链接重定向；/{code}+ 或 ?preview=1 时展示预览页
 // GET /{code}
 func (h *LinkHandler) Redirect(c *gin.Context) {
 	code := c.Param("code")
+	preview := c.Query("preview") == "1"
+	if strings.HasSuffix(code, "+") {
+		code = strings.TrimSuffix(code, "+")
+		preview = true
+	}
 	if code == "" {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "invalid_request",
@@ -67,26 +84,159 @@ func (h *LinkHandler) Redirect(c *gin.Context) {
 		})
 		return
 	}
+	if preview {
+		h.renderPreview(c, code)
+		return
+	}
 
-	longURL, err := h.service.GetLongURL(c.Request.Context(), code)
+	result, err := h.service.GetLongURL(c.Request.Context(), h.newRedirectRequest(c, code))
 	if err != nil {
 		if svcErr, ok := err.(*service.ServiceError); ok {
-			if svcErr.Type == "not_found" {
-				c.JSON(http.StatusNotFound, gin.H{
-					"error":   "not_found",
-					"message": svcErr.Message,
-				})
+			switch svcErr.Type {
+			case "password_required":
+				renderPage(c, http.StatusUnauthorized, passwordPage, gin.H{"Title": "需要密码", "Code": code})
+				return
+			case "preview_required":
+				h.renderPreview(c, code)
 				return
 			}
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "internal_error",
-			"message": "an unexpected error occurred",
+		writeRedirectError(c, err)
+		return
+	}
+
+	h.redirect(c, code, result)
+}
+
+// VerifyPassword 校验受保护短链接的访问密码，通过后重定向
+// POST /{code}
+func (h *LinkHandler) VerifyPassword(c *gin.Context) {
+	code := c.Param("code")
+	password := c.PostForm("password")
+	if password == "" {
+		renderPage(c, http.StatusUnauthorized, passwordPage, gin.H{"Title": "需要密码", "Code": code, "Error": "请输入访问密码"})
+		return
+	}
+
+	req := h.newRedirectRequest(c, code)
+	req.Password = password
+	result, err := h.service.GetLongURL(c.Request.Context(), req)
+	if err != nil {
+		if svcErr, ok := err.(*service.ServiceError); ok {
+			switch svcErr.Type {
+			case "invalid_password":
+				renderPage(c, http.StatusUnauthorized, passwordPage, gin.H{"Title": "需要密码", "Code": code, "Error": "密码错误，请重试"})
+				return
+			case "too_many_attempts":
+				renderPage(c, http.StatusTooManyRequests, passwordPage, gin.H{"Title": "需要密码", "Code": code, "Error": "尝试次数过多，请稍后再试"})
+				return
+			}
+		}
+		writeRedirectError(c, err)
+		return
+	}
+
+	h.redirect(c, code, result)
+}
+
+// renderPreview 渲染预览页，展示目标地址、创建时间与点击次数；不计入点击
+func (h *LinkHandler) renderPreview(c *gin.Context, code string) {
+	info, err := h.service.GetLinkInfo(c.Request.Context(), code, "")
+	if err != nil {
+		writeRedirectError(c, err)
+		return
+	}
+
+	host := ""
+	if parsed, err := url.Parse(info.LongURL); err == nil {
+		host = parsed.Host
+	}
+	renderPage(c, http.StatusOK, previewPage, gin.H{
+		"Title":       "链接预览",
+		"Code":        info.Code,
+		"Host":        host,
+		"LongURL":     info.LongURL,
+		"Protected":   info.PasswordProtected,
+		"Routed":      len(info.Destinations) > 0 || len(info.LanguageURLs) > 0,
+		"CreatedAt":   info.CreatedAt.UTC().Format("2006-01-02 15:04 UTC"),
+		"ClickCount":  info.ClickCount,
+		"ContinueURL": "/" + url.PathEscape(info.Code) + "?confirm=1",
+	})
+}
+
+// newRedirectRequest 从请求中提取重定向所需的访问者信息
+func (h *LinkHandler) newRedirectRequest(c *gin.Context, code string) *service.RedirectRequest {
+	req := &service.RedirectRequest{
+		Code:           code,
+		AcceptLanguage: c.GetHeader("Accept-Language"),
+		Confirmed:      c.Query("confirm") == "1",
+		UserAgent:      c.GetHeader("User-Agent"),
+		Referrer:       c.Request.Referer(),
+		ClientIP:       c.ClientIP(),
+		// 访客通过 Do Not Track 或 Global Privacy Control 表示不希望被跟踪
+		DoNotTrack: c.GetHeader("DNT") == "1" || c.GetHeader("Sec-GPC") == "1",
+	}
+	if h.countryHeader != "" {
+		req.Country = c.GetHeader(h.countryHeader)
+	}
+	// 粘性分流：读取访问者已分配的 A/B 分组
+	if variant, err := c.Cookie(variantCookieName(code)); err == nil {
+		req.Variant = variant
+	}
+	return req
+}
+
+// redirect 写入分流 cookie 与缓存相关头后返回 302；社交平台抓取器返回卡片页
+func (h *LinkHandler) redirect(c *gin.Context, code string, result *service.RedirectResult) {
+	if result.Card != nil {
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		c.Status(http.StatusOK)
+		if err := socialCardPage.Execute(c.Writer, result.Card); err != nil {
+			log.Printf("failed to render social card: %v", err)
+		}
+		return
+	}
+
+	if result.LanguageRouted {
+		c.Header("Vary", "Accept-Language")
+	}
+	if result.Sticky && result.Variant != "" {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(variantCookieName(code), result.Variant, variantCookieMaxAge, "/"+code, "", false, true)
+	}
+	// 归因令牌同时写入 cookie：目标页面加载转化像素时是跨站请求，需要 SameSite=None（必须配合 Secure）
+	if result.AttributionToken != "" {
+		c.SetSameSite(http.SameSiteNoneMode)
+		c.SetCookie(attributionCookieName, result.AttributionToken, int(result.AttributionTTL/time.Second), "/", "", true, true)
+	}
+
+	// 隐藏来源：响应头与页面 meta 同时声明 no-referrer，确保目标站点拿不到 Referer
+	if result.HideReferrer {
+		c.Header("Referrer-Policy", "no-referrer")
+	}
+
+	if result.DeepLink != nil {
+		// app_url 已在创建时校验过 scheme，可以安全地作为链接输出
+		renderPage(c, http.StatusOK, deepLinkPage, gin.H{
+			"Title":      "正在打开应用",
+			"AppURL":     template.URL(result.DeepLink.AppURL),
+			"StoreURL":   result.DeepLink.StoreURL,
+			"WebURL":     result.LongURL,
+			"NoReferrer": result.HideReferrer,
+		})
+		return
+	}
+
+	if result.HideReferrer {
+		renderPage(c, http.StatusOK, refreshPage, gin.H{
+			"Title":      "正在跳转",
+			"RefreshURL": result.LongURL,
+			"NoReferrer": true,
 		})
 		return
 	}
 
-	c.Redirect(http.StatusFound, longURL)
+	c.Redirect(http.StatusFound, result.LongURL)
 }
 
 // GetLinkInfo 获取短链接信息
@@ -101,19 +251,228 @@ func (h *LinkHandler) GetLinkInfo(c *gin.Context) {
 		return
 	}
 
-	info, err := h.service.GetLinkInfo(c.Request.Context(), code)
+	// 受密码保护的链接需通过 X-Link-Password 头提供密码才返回目标地址
+	info, err := h.service.GetLinkInfo(c.Request.Context(), code, c.GetHeader("X-Link-Password"))
 	if err != nil {
-		if svcErr, ok := err.(*service.ServiceError); ok {
-			statusCode := http.StatusInternalServerError
-			if svcErr.Type == "not_found" {
-				statusCode = http.StatusNotFound
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// GetClickStats 按时间桶查询点击统计
+// GET /api/v1/links/{code}/stats?from=&to=&interval=hour|day
+func (h *LinkHandler) GetClickStats(c *gin.Context) {
+	var req service.StatsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	stats, err := h.service.GetClickStats(c.Request.Context(), c.Param("code"), &req)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetBreakdown 按维度查询点击来源排行
+// GET /api/v1/links/{code}/breakdown?dimension=referrer|device|browser|country&limit=
+func (h *LinkHandler) GetBreakdown(c *gin.Context) {
+	limit, ok := queryLimit(c)
+	if !ok {
+		return
+	}
+
+	breakdown, err := h.service.GetBreakdown(c.Request.Context(), c.Param("code"), c.Query("dimension"), limit)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, breakdown)
+}
+
+// StreamClicks 以 Server-Sent Events 推送短链接的实时点击事件
+// GET /api/v1/links/{code}/live
+func (h *LinkHandler) StreamClicks(c *gin.Context) {
+	// 受密码保护的链接需通过 X-Link-Password 头提供密码
+	events, cancel, err := h.service.SubscribeClicks(c.Request.Context(), c.Param("code"), c.GetHeader("X-Link-Password"))
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	h.streamClicks(c, events, cancel)
+}
+
+// StreamAllClicks 以 Server-Sent Events 推送所有短链接的实时点击事件，需 Authorization: Bearer {LIVE_STREAM_TOKEN}
+// GET /api/v1/live
+func (h *LinkHandler) StreamAllClicks(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+	events, cancel, err := h.service.SubscribeAllClicks(c.Request.Context(), token)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+	h.streamClicks(c, events, cancel)
+}
+
+func (h *LinkHandler) streamClicks(c *gin.Context, events <-chan *model.ClickEvent, cancel func()) {
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// 关闭 Nginx 等反向代理的响应缓冲
+	c.Header("X-Accel-Buffering", "no")
+
+	// 定期发送注释行，防止空闲连接被代理断开
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-events:
+			if !ok {
+				return false
 			}
-			c.JSON(statusCode, gin.H{
-				"error":   svcErr.Type,
-				"message": svcErr.Message,
-			})
+			c.SSEvent("click", event)
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		}
+	})
+}
+
+// GetLeaderboard 查询滚动窗口内点击最多的短链接
+// GET /api/v1/leaderboard?window=1h|24h|7d&limit=
+func (h *LinkHandler) GetLeaderboard(c *gin.Context) {
+	limit, ok := queryLimit(c)
+	if !ok {
+		return
+	}
+
+	leaderboard, err := h.service.GetLeaderboard(c.Request.Context(), c.Query("window"), limit)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, leaderboard)
+}
+
+// queryLimit 解析 limit 查询参数（缺省为 0，由 service 使用默认值），格式错误时直接返回 400
+func queryLimit(c *gin.Context) (int, bool) {
+	v := c.Query("limit")
+	if v == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "limit must be an integer",
+		})
+		return 0, false
+	}
+	return n, true
+}
+
+// ListBrokenLinks 列出健康检查发现目标不可用的链接
+// GET /api/v1/reports/broken
+func (h *LinkHandler) ListBrokenLinks(c *gin.Context) {
+	links, err := h.service.ListBrokenLinks(c.Request.Context())
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"count": len(links),
+		"links": links,
+	})
+}
+
+// SetDisabled 停用或重新启用短链接，需通过 X-Link-Password 头提供链接密码
+// POST /api/v1/links/{code}/disable
+// POST /api/v1/links/{code}/enable
+func (h *LinkHandler) SetDisabled(disabled bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		code := c.Param("code")
+		if err := h.service.SetLinkDisabled(c.Request.Context(), code, c.GetHeader("X-Link-Password"), disabled); err != nil {
+			writeServiceError(c, err)
 			return
 		}
+		c.JSON(http.StatusOK, gin.H{"code": code, "disabled": disabled})
+	}
+}
+
+// UpdateLink 修改短链接的目标地址、过期时间等设置，需通过 X-Link-Password 头提供链接密码
+// PATCH /api/v1/links/{code}
+func (h *LinkHandler) UpdateLink(c *gin.Context) {
+	var req service.UpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	info, err := h.service.UpdateLink(c.Request.Context(), c.Param("code"), c.GetHeader("X-Link-Password"), &req)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// DeleteLink 删除短链接，需通过 X-Link-Password 头提供链接密码
+// DELETE /api/v1/links/{code}
+func (h *LinkHandler) DeleteLink(c *gin.Context) {
+	if err := h.service.DeleteLink(c.Request.Context(), c.Param("code"), c.GetHeader("X-Link-Password")); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// serviceErrorStatus 返回业务错误类型对应的 HTTP 状态码
+func serviceErrorStatus(errType string) int {
+	switch errType {
+	case "invalid_request":
+		return http.StatusBadRequest
+	case "conflict":
+		return http.StatusConflict
+	case "not_found":
+		return http.StatusNotFound
+	case "not_active", "forbidden":
+		return http.StatusForbidden
+	case "expired", "exhausted", "disabled":
+		return http.StatusGone
+	case "password_required", "invalid_password", "unauthorized":
+		return http.StatusUnauthorized
+	case "too_many_attempts":
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
+
+// writeServiceError 将业务错误转换为统一的 JSON 错误响应
+func writeServiceError(c *gin.Context, err error) {
+	svcErr, ok := err.(*service.ServiceError)
+	if !ok {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "internal_error",
 			"message": "an unexpected error occurred",
@@ -121,5 +480,38 @@ func (h *LinkHandler) GetLinkInfo(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, info)
+	c.JSON(serviceErrorStatus(svcErr.Type), gin.H{
+		"error":   svcErr.Type,
+		"message": svcErr.Message,
+	})
+}
+
+// writeRedirectError 短链接访问路径上的错误响应：浏览器（Accept 优先 text/html）返回 HTML 错误页，其余返回 JSON
+func writeRedirectError(c *gin.Context, err error) {
+	// 响应格式取决于 Accept，避免共享缓存把 HTML 错误页返回给 API 客户端（或相反）
+	c.Writer.Header().Add("Vary", "Accept")
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) != gin.MIMEHTML {
+		writeServiceError(c, err)
+		return
+	}
+
+	statusCode := http.StatusInternalServerError
+	heading := "服务暂时不可用"
+	message := "请稍后重试"
+	if svcErr, ok := err.(*service.ServiceError); ok {
+		statusCode = serviceErrorStatus(svcErr.Type)
+		if texts, ok := errorTexts[svcErr.Type]; ok {
+			heading, message = texts[0], texts[1]
+		}
+		// 未生效提示可由链接自定义（inactive_message）
+		if svcErr.Type == "not_active" {
+			message = svcErr.Message
+		}
+	}
+	renderPage(c, statusCode, errorPage, gin.H{"Title": heading, "Heading": heading, "Message": message})
+}
+
+// variantCookieName 返回记录某短码 A/B 分组的 cookie 名
+func variantCookieName(code string) string {
+	return "sl_variant_" + code
 }
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"html/template"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// layoutHTML 面向浏览器访问者的页面骨架，各页面通过 {{define "content"}} 填充主体
+const layoutHTML = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+{{if .NoReferrer}}<meta name="referrer" content="no-referrer">{{end}}
+{{if .RefreshURL}}<meta http-equiv="refresh" content="0;url={{.RefreshURL}}">{{end}}
+<title>{{.Title}}</title>
+<style>
+body { margin: 0; min-height: 100vh; display: flex; align-items: center; justify-content: center; background: #f5f7fb; font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", "PingFang SC", "Microsoft YaHei", sans-serif; color: #1f2937; }
+.card { width: 100%; max-width: 420px; margin: 16px; padding: 32px; background: #fff; border-radius: 12px; box-shadow: 0 10px 30px rgba(15, 23, 42, 0.08); }
+h1 { margin: 0 0 8px; font-size: 22px; }
+p { line-height: 1.6; }
+.muted { color: #6b7280; font-size: 14px; }
+.error { padding: 10px 12px; border-radius: 8px; background: #fef2f2; color: #b91c1c; font-size: 14px; }
+input { width: 100%; box-sizing: border-box; padding: 10px 12px; margin: 12px 0; border: 1px solid #d1d5db; border-radius: 8px; font-size: 15px; }
+button, .button { display: inline-block; width: 100%; box-sizing: border-box; padding: 10px 12px; border: none; border-radius: 8px; background: #2563eb; color: #fff; font-size: 15px; text-align: center; text-decoration: none; cursor: pointer; }
+</style>
+</head>
+<body>
+<div class="card">
+{{template "content" .}}
+</div>
+</body>
+</html>`
+
+var layoutTemplate = template.Must(template.New("layout").Parse(layoutHTML))
+
+// newPage 基于页面骨架创建一个页面模板
+func newPage(content string) *template.Template {
+	return template.Must(template.Must(layoutTemplate.Clone()).Parse(content))
+}
+
+// passwordPage 受密码保护链接的密码输入页，表单 POST 到当前短链接地址
+var passwordPage = newPage(`{{define "content"}}
+<h1>此链接受密码保护</h1>
+<p class="muted">请输入访问密码以继续访问短链接 <strong>{{.Code}}</strong>。</p>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post">
+<input type="password" name="password" placeholder="访问密码" autocomplete="current-password" autofocus required>
+<button type="submit">继续访问</button>
+</form>
+{{end}}`)
+
+// previewPage 短链接预览（中间页），展示目标地址后由访问者确认继续
+var previewPage = newPage(`{{define "content"}}
+<h1>即将离开并访问外部链接</h1>
+{{if .Protected}}
+<p class="muted">短链接 <strong>{{.Code}}</strong> 受密码保护，目标地址需输入密码后才能查看。</p>
+{{else}}
+<p class="muted">目标网站</p>
+<p><strong>{{.Host}}</strong></p>
+<p class="muted">完整地址</p>
+<p style="word-break: break-all;">{{.LongURL}}</p>
+{{if .Routed}}<p class="muted">该链接会根据访问者的语言或分组跳转到不同地址，以上为默认地址。</p>{{end}}
+{{end}}
+<p class="muted">创建于 {{.CreatedAt}} · 已被访问 {{.ClickCount}} 次</p>
+<a class="button" href="{{.ContinueURL}}" rel="noreferrer">继续访问</a>
+{{end}}`)
+
+// socialCardHTML 返回给社交平台抓取器的卡片页（OpenGraph/Twitter Card），不使用通用页面骨架
+const socialCardHTML = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta property="og:type" content="website">
+<meta property="og:url" content="{{.ShortURL}}">
+{{if .Title}}<meta property="og:title" content="{{.Title}}">
+<meta name="twitter:title" content="{{.Title}}">{{end}}
+{{if .Description}}<meta name="description" content="{{.Description}}">
+<meta property="og:description" content="{{.Description}}">
+<meta name="twitter:description" content="{{.Description}}">{{end}}
+{{if .Image}}<meta property="og:image" content="{{.Image}}">
+<meta name="twitter:image" content="{{.Image}}">
+<meta name="twitter:card" content="summary_large_image">{{else}}<meta name="twitter:card" content="summary">{{end}}
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p>{{.Description}}</p>
+{{if .TargetURL}}<p><a href="{{.TargetURL}}">{{.TargetURL}}</a></p>{{end}}
+</body>
+</html>`
+
+var socialCardPage = template.Must(template.New("card").Parse(socialCardHTML))
+
+// refreshPage 通过 meta refresh 跳转的中间页（隐藏来源时使用）
+var refreshPage = newPage(`{{define "content"}}
+<h1>正在跳转…</h1>
+<p class="muted">如果没有自动跳转，请点击下方按钮。</p>
+<a class="button" href="{{.RefreshURL}}" rel="noreferrer">继续访问</a>
+{{end}}`)
+
+// deepLinkPage 移动端打开 App 的中间页：先尝试唤起 App，超时未离开页面则跳转网页地址
+var deepLinkPage = newPage(`{{define "content"}}
+<h1>正在打开应用…</h1>
+<p class="muted">如果没有自动打开，请点击下方按钮。</p>
+<p><a class="button" href="{{.AppURL}}">在应用中打开</a></p>
+{{if .StoreURL}}<p><a class="button" style="background:#111827" href="{{.StoreURL}}">尚未安装？前往应用商店下载</a></p>{{end}}
+<p class="muted"><a href="{{.WebURL}}" rel="noreferrer">继续在浏览器中访问</a></p>
+<noscript><meta http-equiv="refresh" content="0;url={{.WebURL}}"></noscript>
+<script>
+(function () {
+  var webURL = {{.WebURL}};
+  var timer = setTimeout(function () { window.location.replace(webURL); }, 1500);
+  // App 被唤起后页面进入后台，取消回退跳转
+  document.addEventListener("visibilitychange", function () {
+    if (document.hidden) { clearTimeout(timer); }
+  });
+  window.location.href = {{.AppURL}};
+})();
+</script>
+{{end}}`)
+
+// errorPage 面向浏览器的错误页
+var errorPage = newPage(`{{define "content"}}
+<h1>{{.Heading}}</h1>
+<p class="muted">{{.Message}}</p>
+{{end}}`)
+
+// errorTexts 各错误类型在错误页上的标题与说明
+var errorTexts = map[string][2]string{
+	"not_found":         {"短链接不存在", "请检查链接是否输入正确。"},
+	"expired":           {"短链接已过期", "该链接已超过有效期，无法继续访问。"},
+	"exhausted":         {"短链接访问次数已用完", "该链接的可访问次数已达到上限。"},
+	"disabled":          {"短链接已停用", "该链接已被管理员停用。"},
+	"not_active":        {"短链接尚未生效", "该链接当前不在可访问时间内，请稍后再试。"},
+	"too_many_attempts": {"尝试次数过多", "请稍后再试。"},
+}
+
+// renderPage 渲染 HTML 页面，页面内容与访问者相关，禁止缓存
+func renderPage(c *gin.Context, status int, page *template.Template, data gin.H) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "no-store")
+	c.Status(status)
+	if err := page.ExecuteTemplate(c.Writer, "layout", data); err != nil {
+		log.Printf("failed to render page: %v", err)
+	}
+}
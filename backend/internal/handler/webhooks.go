@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"url-shortener/backend/internal/service"
+)
+
+// 以下接口都需通过 X-Link-Password 头提供链接密码
+
+// CreateWebhook 在短链接上注册 webhook，响应中的 secret 只返回这一次
+// POST /api/v1/links/{code}/webhooks
+func (h *LinkHandler) CreateWebhook(c *gin.Context) {
+	var req service.WebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	hook, err := h.service.CreateWebhook(c.Request.Context(), c.Param("code"), c.GetHeader("X-Link-Password"), &req)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, hook)
+}
+
+// ListWebhooks 列出短链接上注册的 webhook
+// GET /api/v1/links/{code}/webhooks
+func (h *LinkHandler) ListWebhooks(c *gin.Context) {
+	hooks, err := h.service.ListWebhooks(c.Request.Context(), c.Param("code"), c.GetHeader("X-Link-Password"))
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"webhooks": hooks})
+}
+
+// DeleteWebhook 删除 webhook
+// DELETE /api/v1/links/{code}/webhooks/{id}
+func (h *LinkHandler) DeleteWebhook(c *gin.Context) {
+	if err := h.service.DeleteWebhook(c.Request.Context(), c.Param("code"), c.GetHeader("X-Link-Password"), c.Param("id")); err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries 查询 webhook 最近的投递记录
+// GET /api/v1/links/{code}/webhooks/{id}/deliveries?limit=
+func (h *LinkHandler) ListWebhookDeliveries(c *gin.Context) {
+	limit, ok := queryLimit(c)
+	if !ok {
+		return
+	}
+
+	deliveries, err := h.service.ListWebhookDeliveries(c.Request.Context(), c.Param("code"), c.GetHeader("X-Link-Password"), c.Param("id"), limit)
+	if err != nil {
+		writeServiceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
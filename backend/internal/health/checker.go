@@ -0,0 +1,219 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/safehttp"
+	"url-shortener/backend/internal/storage"
+)
+
+const (
+	defaultInterval    = time.Minute
+	defaultConcurrency = 8
+	// maxBackoffShift 连续失败时检查间隔最多放大到 2^4 = 16 倍
+	maxBackoffShift = 4
+)
+
+// Options 健康检查配置，零值字段使用默认值
+type Options struct {
+	// Interval 两轮检查之间的间隔
+	Interval time.Duration
+	// Concurrency 同时探测的链接数上限
+	Concurrency int
+	// Timeout 单次探测超时
+	Timeout time.Duration
+}
+
+// Checker 后台健康检查：定期遍历所有链接，探测主链接、各个分组目标与语言路由目标，
+// 把结果（状态、检查时间、连续失败次数）写回记录，并维护失效链接集合。
+// failover 链接据此选择第一个可用目标
+type Checker struct {
+	repo        storage.LinkRepository
+	client      *http.Client
+	interval    time.Duration
+	concurrency int
+}
+
+func NewChecker(repo storage.LinkRepository, opts Options) *Checker {
+	if opts.Interval <= 0 {
+		opts.Interval = defaultInterval
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultConcurrency
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &Checker{
+		repo:        repo,
+		client:      safehttp.NewClient(safehttp.Options{Timeout: opts.Timeout}),
+		interval:    opts.Interval,
+		concurrency: opts.Concurrency,
+	}
+}
+
+// Run 按间隔执行检查，直到 ctx 取消
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		c.checkAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkAll 遍历所有链接，交给固定数量的 worker 并发检查
+func (c *Checker) checkAll(ctx context.Context) {
+	links := make(chan *model.ShortLink)
+	var wg sync.WaitGroup
+	for i := 0; i < c.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range links {
+				c.checkLink(ctx, link)
+			}
+		}()
+	}
+
+	err := c.repo.ForEachLink(ctx, func(link *model.ShortLink) error {
+		select {
+		case links <- link:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	close(links)
+	wg.Wait()
+
+	if err != nil && ctx.Err() == nil {
+		log.Printf("health check iteration failed: %v", err)
+	}
+}
+
+// checkLink 探测一个链接的主链接、所有分组目标与语言路由目标，任一不可用即标记为失效。
+// 已禁用或已过期的链接不再检查，并移出失效集合
+func (c *Checker) checkLink(ctx context.Context, link *model.ShortLink) {
+	now := time.Now().UTC()
+	if link.Disabled || (link.ExpireAt != nil && now.After(*link.ExpireAt)) {
+		c.setBroken(ctx, link.Code, false)
+		return
+	}
+
+	broken := false
+	check := func(destinationID, rawURL string, prev *model.HealthStatus) {
+		status := prev
+		if c.due(prev, now) {
+			status = c.probe(ctx, rawURL, prev)
+			if ctx.Err() != nil {
+				return
+			}
+			if err := c.repo.SetHealth(ctx, link.Code, destinationID, status); err != nil {
+				log.Printf("failed to save health of %s/%s: %v", link.Code, destinationID, err)
+			}
+		}
+		if status != nil && !status.Up {
+			broken = true
+		}
+	}
+
+	// 多目标链接的 LongURL 即第一个目标，无需重复探测
+	if len(link.Destinations) == 0 {
+		check("", link.LongURL, link.Health)
+	}
+	for _, d := range link.Destinations {
+		check(d.ID, d.URL, d.Health)
+	}
+	for tag, u := range link.LanguageURLs {
+		check(model.LanguageHealthID(tag), u, link.LanguageHealth[tag])
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	c.setBroken(ctx, link.Code, broken)
+}
+
+// due 判断目标是否需要在本轮检查：连续失败的目标按 interval × 2^n 退避
+func (c *Checker) due(prev *model.HealthStatus, now time.Time) bool {
+	if prev == nil || prev.ConsecutiveFailures == 0 {
+		return true
+	}
+	shift := prev.ConsecutiveFailures
+	if shift > maxBackoffShift {
+		shift = maxBackoffShift
+	}
+	wait := c.interval << shift
+	// 留出少量余量，避免因调度抖动整轮错过
+	return now.Sub(prev.CheckedAt) >= wait-c.interval/2
+}
+
+func (c *Checker) setBroken(ctx context.Context, code string, broken bool) {
+	if err := c.repo.SetBroken(ctx, code, broken); err != nil && ctx.Err() == nil {
+		log.Printf("failed to update broken flag of %s: %v", code, err)
+	}
+}
+
+// probe 先发 HEAD，目标不支持时改用 GET；状态码小于 400 视为可用
+func (c *Checker) probe(ctx context.Context, rawURL string, prev *model.HealthStatus) *model.HealthStatus {
+	statusCode, err := c.request(ctx, http.MethodHead, rawURL)
+	if err == nil && (statusCode == http.StatusMethodNotAllowed || statusCode == http.StatusNotImplemented) {
+		statusCode, err = c.request(ctx, http.MethodGet, rawURL)
+	}
+
+	status := &model.HealthStatus{
+		StatusCode: statusCode,
+		CheckedAt:  time.Now().UTC(),
+	}
+	switch {
+	case err != nil:
+		// url.Error 的文本带有完整的目标地址，只保存底层原因
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		status.Error = err.Error()
+	case statusCode >= http.StatusBadRequest:
+		status.Error = fmt.Sprintf("unexpected status %d", statusCode)
+	default:
+		status.Up = true
+	}
+	if !status.Up {
+		status.ConsecutiveFailures = 1
+		if prev != nil {
+			status.ConsecutiveFailures = prev.ConsecutiveFailures + 1
+		}
+	}
+	return status
+}
+
+func (c *Checker) request(ctx context.Context, method, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "URLShortenerHealthCheck/1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	// 读取少量响应体以便复用连接
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
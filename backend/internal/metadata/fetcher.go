@@ -0,0 +1,177 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/safehttp"
+)
+
+// ErrNotHTML 目标返回的不是 HTML 页面
+var ErrNotHTML = errors.New("destination is not an html page")
+
+// Options 抓取器配置
+type Options struct {
+	// Timeout 单次抓取（含重定向）的总超时
+	Timeout time.Duration
+	// MaxBodyBytes 最多读取的响应体字节数
+	MaxBodyBytes int64
+	// MaxRedirects 最多跟随的重定向次数
+	MaxRedirects int
+	// AllowPrivateNetworks 允许访问内网地址（仅用于本地调试，生产环境必须关闭）
+	AllowPrivateNetworks bool
+}
+
+// Fetcher 抓取目标页面的标题、描述、og:image 与最终跳转地址（使用防 SSRF 的 HTTP 客户端）
+type Fetcher struct {
+	client       *http.Client
+	maxBodyBytes int64
+}
+
+func NewFetcher(opts Options) *Fetcher {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = 1 << 20
+	}
+	return &Fetcher{
+		client: safehttp.NewClient(safehttp.Options{
+			Timeout:              opts.Timeout,
+			MaxRedirects:         opts.MaxRedirects,
+			AllowPrivateNetworks: opts.AllowPrivateNetworks,
+		}),
+		maxBodyBytes: opts.MaxBodyBytes,
+	}
+}
+
+// Fetch 抓取目标页面元数据；非 HTML 页面只返回最终地址
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*model.PageMetadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "URLShortenerBot/1.0 (+metadata fetch)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	meta := &model.PageMetadata{
+		FinalURL:  resp.Request.URL.String(),
+		FetchedAt: time.Now().UTC(),
+	}
+	if resp.StatusCode >= 400 {
+		return meta, fmt.Errorf("destination returned status %d", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil &&
+		mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return meta, ErrNotHTML
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.maxBodyBytes), contentType)
+	if err != nil {
+		return meta, err
+	}
+	parseHead(body, resp.Request.URL, meta)
+	return meta, nil
+}
+
+// parseHead 从 HTML 中提取 <title>、description、og:* 信息，遇到 <body> 或读取上限即停止
+func parseHead(r io.Reader, base *url.URL, meta *model.PageMetadata) {
+	var title, ogTitle, description, ogDescription, image string
+	z := html.NewTokenizer(r)
+	inTitle := false
+
+loop:
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			break loop
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = true
+			case "body":
+				break loop
+			case "meta":
+				if !hasAttr {
+					continue
+				}
+				var key, content string
+				for {
+					attrName, attrValue, more := z.TagAttr()
+					switch strings.ToLower(string(attrName)) {
+					case "name", "property":
+						key = strings.ToLower(string(attrValue))
+					case "content":
+						content = string(attrValue)
+					}
+					if !more {
+						break
+					}
+				}
+				switch key {
+				case "description":
+					description = content
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDescription = content
+				case "og:image", "og:image:url", "twitter:image":
+					if image == "" {
+						image = content
+					}
+				}
+			}
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = string(z.Text())
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "title" {
+				inTitle = false
+			}
+		}
+	}
+
+	meta.Title = truncate(firstNonEmpty(ogTitle, title), 300)
+	meta.Description = truncate(firstNonEmpty(ogDescription, description), 1000)
+	if image != "" {
+		if ref, err := base.Parse(strings.TrimSpace(image)); err == nil && (ref.Scheme == "http" || ref.Scheme == "https") {
+			meta.Image = ref.String()
+		}
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// truncate 按字符数截断字符串
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
@@ -0,0 +1,123 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"url-shortener/backend/internal/safehttp"
+)
+
+func TestFetch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/og", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(`<html><head>
+<title>Plain title</title>
+<meta property="og:title" content="OG title">
+<meta name="description" content="Plain description">
+<meta property="og:image" content="/img/cover.png">
+</head><body><title>ignored</title></body></html>`))
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title> Plain title </title>
+<meta property="og:description" content="OG description">
+</head></html>`))
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/plain", http.StatusFound)
+	})
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"title":"no"}`))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	fetcher := NewFetcher(Options{AllowPrivateNetworks: true})
+	tests := []struct {
+		name            string
+		path            string
+		wantErr         error
+		wantAnyErr      bool
+		wantFinalPath   string
+		wantTitle       string
+		wantDescription string
+		wantImage       string
+	}{
+		{
+			name:            "og tags preferred",
+			path:            "/og",
+			wantFinalPath:   "/og",
+			wantTitle:       "OG title",
+			wantDescription: "Plain description",
+			wantImage:       srv.URL + "/img/cover.png",
+		},
+		{
+			name:            "title fallback",
+			path:            "/plain",
+			wantFinalPath:   "/plain",
+			wantTitle:       "Plain title",
+			wantDescription: "OG description",
+		},
+		{
+			name:            "follows redirects",
+			path:            "/redirect",
+			wantFinalPath:   "/plain",
+			wantTitle:       "Plain title",
+			wantDescription: "OG description",
+		},
+		{name: "not html", path: "/json", wantErr: ErrNotHTML, wantFinalPath: "/json"},
+		{name: "error status", path: "/missing", wantAnyErr: true, wantFinalPath: "/missing"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, err := fetcher.Fetch(context.Background(), srv.URL+tt.path)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Fetch error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.wantAnyErr:
+				if err == nil {
+					t.Fatal("Fetch returned nil error")
+				}
+			case err != nil:
+				t.Fatalf("Fetch: %v", err)
+			}
+			if meta == nil {
+				t.Fatal("Fetch returned nil metadata")
+			}
+			if meta.FinalURL != srv.URL+tt.wantFinalPath {
+				t.Errorf("FinalURL = %q, want %q", meta.FinalURL, srv.URL+tt.wantFinalPath)
+			}
+			if meta.Title != tt.wantTitle {
+				t.Errorf("Title = %q, want %q", meta.Title, tt.wantTitle)
+			}
+			if meta.Description != tt.wantDescription {
+				t.Errorf("Description = %q, want %q", meta.Description, tt.wantDescription)
+			}
+			if meta.Image != tt.wantImage {
+				t.Errorf("Image = %q, want %q", meta.Image, tt.wantImage)
+			}
+		})
+	}
+}
+
+func TestFetchRejectsPrivateAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the private server")
+	}))
+	defer srv.Close()
+
+	_, err := NewFetcher(Options{}).Fetch(context.Background(), srv.URL)
+	if !errors.Is(err, safehttp.ErrBlockedAddress) {
+		t.Fatalf("Fetch error = %v, want %v", err, safehttp.ErrBlockedAddress)
+	}
+}
@@ -0,0 +1,103 @@
+package model
+
+import "time"
+
+// User-Agent 分类
+const (
+	UAClassDesktop = "desktop"
+	UAClassMobile  = "mobile"
+	UAClassTablet  = "tablet"
+	UAClassBot     = "bot"
+	UAClassUnknown = "unknown"
+)
+
+// ClickEvent 表示一次重定向产生的点击事件
+type ClickEvent struct {
+	// ID 为存储分配的事件 ID（Redis Stream entry ID），写入前为空
+	ID        string    `json:"id,omitempty"`
+	Code      string    `json:"code"`
+	Variant   string    `json:"variant,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	// ReferrerHost 为来源页面的域名（无 Referer 时为空）
+	ReferrerHost string `json:"referrer_host,omitempty"`
+	// UserAgentClass 为访问设备分类：desktop / mobile / tablet / bot / unknown
+	UserAgentClass string `json:"ua_class"`
+	// Bot 为 true 表示访问来自机器人，不计入人工点击统计
+	Bot bool `json:"bot"`
+	// Browser 为浏览器家族：chrome / edge / firefox / safari / opera / samsung / other
+	Browser string `json:"browser"`
+	// Country 为 CDN/代理提供的 ISO 3166-1 国家代码（未知时为空）
+	Country string `json:"country,omitempty"`
+	// IP 为按隐私策略处理后的访问者 IP（截断后的网段或 HMAC 哈希），不保存完整地址
+	IP string `json:"ip,omitempty"`
+	// VisitorID 为访客指纹（带密钥的 IP + User-Agent 哈希），只用于独立访客计数，不写入事件流
+	VisitorID string `json:"-"`
+
+	// NoTrack 为 true 表示访客要求不跟踪：只累加计数，不保存事件与访客信息
+	NoTrack bool `json:"-"`
+}
+
+// 统计桶粒度
+const (
+	StatsIntervalHour = "hour"
+	StatsIntervalDay  = "day"
+)
+
+// StatsBucket 一个时间桶内的点击统计
+type StatsBucket struct {
+	// Start 为桶的起始时间（UTC，按小时或按天对齐）
+	Start  time.Time `json:"start"`
+	Clicks int64     `json:"clicks"`
+	// BotClicks 为桶内机器人访问次数（不计入 Clicks）
+	BotClicks int64 `json:"bot_clicks"`
+	// Conversions 为桶内归因到该短码的转化次数（按转化发生时间计入）
+	Conversions int64 `json:"conversions"`
+	// Uniques 为桶内独立访客数（仅按天统计时提供）
+	Uniques *int64 `json:"uniques,omitempty"`
+}
+
+// 点击来源统计维度
+const (
+	DimensionReferrer = "referrer"
+	DimensionDevice   = "device"
+	DimensionBrowser  = "browser"
+	DimensionCountry  = "country"
+)
+
+// BreakdownItem 某个维度取值的点击数
+type BreakdownItem struct {
+	Value  string `json:"value"`
+	Clicks int64  `json:"clicks"`
+}
+
+// 排行榜时间窗口
+const (
+	LeaderboardHour = "1h"
+	LeaderboardDay  = "24h"
+	LeaderboardWeek = "7d"
+)
+
+// RankedLink 排行榜中的短码及其窗口内的点击数
+type RankedLink struct {
+	Code   string `json:"code"`
+	Clicks int64  `json:"clicks"`
+}
+
+// Attribution 重定向时签发的归因令牌，用于把目标站点上报的转化关联回短链接的点击
+type Attribution struct {
+	Token     string    `json:"token"`
+	Code      string    `json:"code"`
+	Variant   string    `json:"variant,omitempty"`
+	ClickedAt time.Time `json:"clicked_at"`
+}
+
+// Conversion 目标站点上报的一次转化
+type Conversion struct {
+	Token string `json:"token"`
+	// Code / Variant 由归因令牌解析得到
+	Code      string    `json:"code"`
+	Variant   string    `json:"variant,omitempty"`
+	Goal      string    `json:"goal"`
+	Value     float64   `json:"value,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
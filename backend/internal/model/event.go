@@ -0,0 +1,27 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 领域事件类型
+const (
+	EventLinkCreated = "link.created"
+	EventLinkUpdated = "link.updated"
+	EventLinkDeleted = "link.deleted"
+	EventLinkClicked = "link.clicked"
+	EventLinkExpired = "link.expired"
+)
+
+// DomainEvent 短链接生命周期中的领域事件，与触发它的存储写入在同一事务中写入 outbox，
+// 再由 events.Relay 投递给各个 sink（至少投递一次，下游需按 ID 去重）
+type DomainEvent struct {
+	// ID 为 outbox 条目 ID（写入时生成，按时间递增）
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	Code       string    `json:"code"`
+	OccurredAt time.Time `json:"occurred_at"`
+	// Data 事件内容：link.created / updated / deleted / expired 为链接信息，link.clicked 为点击事件（不含 IP）
+	Data json.RawMessage `json:"data,omitempty"`
+}
@@ -4,12 +4,127 @@ import "time"
 
 // ShortLink 表示一条短链接记录
 type ShortLink struct {
-	ID             int64      `db:"id"`
-	Code           string     `db:"code"`
-	LongURL        string     `db:"long_url"`
-	CreatedAt      time.Time  `db:"created_at"`
-	ExpireAt       *time.Time `db:"expire_at"`
-	ClickCount     int64      `db:"click_count"`
+	ID         int64      `db:"id"`
+	Code       string     `db:"code"`
+	LongURL    string     `db:"long_url"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ExpireAt   *time.Time `db:"expire_at"`
+	ClickCount int64      `db:"click_count"`
+	// BotClickCount 机器人（爬虫、预览抓取器、监控）的访问次数，不计入 ClickCount
+	BotClickCount  int64      `db:"bot_click_count"`
 	LastAccessedAt *time.Time `db:"last_accessed_at"`
+
+	// Destinations 为多个目标地址（为空时只使用 LongURL），按 RoutingMode 选择
+	Destinations []Destination `db:"destinations"`
+	// RoutingMode 多目标的选择方式：weighted（按权重随机，默认）、round_robin（轮询）、failover（故障转移）
+	RoutingMode string `db:"routing_mode"`
+	// StickyVariant 为 true 时，同一访问者通过 cookie 固定命中同一分组
+	StickyVariant bool `db:"sticky_variant"`
+	// LanguageURLs 按语言标签（如 zh-CN、en）路由的目标地址，未匹配时回退到默认目标
+	LanguageURLs map[string]string `db:"language_urls"`
+
+	// ActivateAt 生效时间，之前访问视为未生效
+	ActivateAt *time.Time `db:"activate_at"`
+	// Schedule 周期性生效窗口（如工作日 9:00-18:00），为空表示全天生效
+	Schedule *Schedule `db:"schedule"`
+	// FallbackURL 链接未生效、已过期、已停用或点击次数用完时重定向的备用地址
+	FallbackURL string `db:"fallback_url"`
+	// InactiveMessage 未生效且无备用地址时返回的提示信息
+	InactiveMessage string `db:"inactive_message"`
+
+	// MaxClicks 最大点击次数，达到后链接失效（0 表示不限制，1 即一次性链接）
+	MaxClicks int64 `db:"max_clicks"`
+
+	// PasswordHash 访问密码的 bcrypt 哈希（含盐），为空表示无需密码
+	PasswordHash string `db:"password_hash"`
+
+	// ForcePreview 为 true 时所有访问者都先看到预览页，确认后才跳转
+	ForcePreview bool `db:"force_preview"`
+	// Disabled 为 true 时链接被停用，不再重定向
+	Disabled bool `db:"disabled"`
+
+	// OGTitle/OGDescription/OGImage 社交平台抓取短链接时展示的卡片信息（OpenGraph/Twitter Card）
+	OGTitle       string `db:"og_title"`
+	OGDescription string `db:"og_description"`
+	OGImage       string `db:"og_image"`
+
+	// AppURL App 深度链接（如 myapp://product/42），移动端访问时优先尝试打开 App，失败后回退到网页地址
+	AppURL string `db:"app_url"`
+	// IOSStoreURL/AndroidStoreURL 未安装 App 时展示的应用商店地址
+	IOSStoreURL     string `db:"ios_store_url"`
+	AndroidStoreURL string `db:"android_store_url"`
+
+	// HideReferrer 为 true 时通过 meta refresh 页面跳转并禁止发送 Referer，目标站点无法得知来源页面
+	HideReferrer bool `db:"hide_referrer"`
+	// TrackConversions 为 true 时重定向会附带归因令牌（sl_ref 查询参数与 cookie），用于转化追踪
+	TrackConversions bool `db:"track_conversions"`
+
+	// Metadata 创建后异步抓取的目标页面信息（抓取完成前为空）
+	Metadata *PageMetadata `db:"metadata"`
+	// Health 主链接（LongURL）最近一次健康检查结果（尚未检查时为空）
+	Health *HealthStatus `db:"health"`
+	// LanguageHealth 语言路由各目标地址最近一次健康检查结果，键为语言标签（尚未检查的标签不在其中），
+	// 按标签分别存储，不对应单个字段
+	LanguageHealth map[string]*HealthStatus `db:"-"`
+}
+
+// LanguageHealthID 返回语言路由目标在健康检查结果中的标识，与分组 ID（仅字母数字）不会冲突
+func LanguageHealthID(tag string) string {
+	return "lang:" + tag
 }
 
+// PageMetadata 目标页面的元数据
+type PageMetadata struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	// FinalURL 跟随重定向后的最终地址
+	FinalURL  string    `json:"final_url,omitempty"`
+	FetchedAt time.Time `json:"fetched_at"`
+	// Error 抓取失败的原因（成功时为空）
+	Error string `json:"error,omitempty"`
+}
+
+// Schedule 周期性生效窗口
+type Schedule struct {
+	// Timezone IANA 时区名（如 Asia/Shanghai），为空表示 UTC
+	Timezone string           `json:"timezone,omitempty"`
+	Windows  []ScheduleWindow `json:"windows"`
+}
+
+// ScheduleWindow 一个生效时间段，Start/End 为 HH:MM，End 早于 Start 表示跨零点
+type ScheduleWindow struct {
+	// Days 生效的星期（mon-sun、weekdays、weekends），为空表示每天
+	Days  []string `json:"days,omitempty"`
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+}
+
+// 多目标链接的选择方式
+const (
+	RoutingWeighted   = "weighted"
+	RoutingRoundRobin = "round_robin"
+	RoutingFailover   = "failover"
+)
+
+// Destination 表示多目标链接中的一个目标地址（分组）
+type Destination struct {
+	ID         string `json:"id"`
+	URL        string `json:"url"`
+	Weight     int    `json:"weight"`
+	ClickCount int64  `json:"-"`
+	// Health 最近一次健康检查结果（尚未检查时为空，视为可用）
+	Health *HealthStatus `json:"-"`
+}
+
+// HealthStatus 目标地址的健康检查结果
+type HealthStatus struct {
+	Up bool `json:"up"`
+	// StatusCode 最近一次探测的 HTTP 状态码（请求失败时为 0）
+	StatusCode int `json:"status_code,omitempty"`
+	// Error 最近一次探测失败的原因
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+	// ConsecutiveFailures 连续探测失败次数，成功后清零
+	ConsecutiveFailures int `json:"consecutive_failures"`
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook 事件类型
+const (
+	WebhookEventCreated        = "link.created"
+	WebhookEventUpdated        = "link.updated"
+	WebhookEventDeleted        = "link.deleted"
+	WebhookEventExpired        = "link.expired"
+	WebhookEventClickThreshold = "link.click_threshold"
+)
+
+// WebhookEvents 所有可订阅的事件类型
+var WebhookEvents = []string{
+	WebhookEventCreated,
+	WebhookEventUpdated,
+	WebhookEventDeleted,
+	WebhookEventExpired,
+	WebhookEventClickThreshold,
+}
+
+// Webhook 某个短链接上注册的事件推送地址
+type Webhook struct {
+	ID   string `json:"id"`
+	Code string `json:"code"`
+	URL  string `json:"url"`
+	// Secret 签名密钥，只在创建时返回一次
+	Secret string   `json:"-"`
+	Events []string `json:"events"`
+	// ClickThreshold 点击次数达到该值时推送 link.click_threshold（0 表示不推送）
+	ClickThreshold int64     `json:"click_threshold,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Subscribes 判断 webhook 是否订阅了某个事件
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// 投递状态
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery 一次事件推送及其重试状态；URL 与 Secret 在入队时从 webhook 复制，
+// 之后删除 webhook 或短链接也不影响已入队的推送
+type WebhookDelivery struct {
+	ID        string          `json:"id"`
+	WebhookID string          `json:"webhook_id"`
+	Code      string          `json:"code"`
+	Event     string          `json:"event"`
+	URL       string          `json:"url"`
+	Secret    string          `json:"-"`
+	Payload   json.RawMessage `json:"payload"`
+	Status    string          `json:"status"`
+	Attempts  int             `json:"attempts"`
+	// ResponseStatus / LastError 为最近一次尝试的 HTTP 状态码与失败原因
+	ResponseStatus int        `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+}
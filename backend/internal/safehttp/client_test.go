@@ -0,0 +1,100 @@
+package safehttp
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"8.8.8.8", true},
+		{"1.1.1.1", true},
+		{"2606:4700:4700::1111", true},
+		{"10.0.0.1", false},
+		{"172.16.5.4", false},
+		{"192.168.1.1", false},
+		{"127.0.0.1", false},
+		{"0.0.0.0", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"198.18.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::1", false},
+		{"::", false},
+		{"fc00::1", false},
+		{"fe80::1", false},
+		{"::ffff:10.0.0.1", false},
+		{"::ffff:8.8.8.8", true},
+		{"64:ff9b::a00:1", false},
+		{"2001:db8::1", false},
+	}
+	for _, tt := range tests {
+		if got := IsPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("IsPublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestClientRejectsPrivateAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr error
+	}{
+		{"blocked by default", Options{}, ErrBlockedAddress},
+		{"allowed for local debugging", Options{AllowPrivateNetworks: true}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := NewClient(tt.opts).Get(srv.URL)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+			if tt.wantErr == nil {
+				if err != nil {
+					t.Fatalf("Get: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientRedirectPolicy(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/loop":
+			http.Redirect(w, r, srv.URL+"/loop", http.StatusFound)
+		case "/ftp":
+			http.Redirect(w, r, "ftp://example.com/file", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer srv.Close()
+
+	client := NewClient(Options{MaxRedirects: 2, AllowPrivateNetworks: true})
+	for _, path := range []string{"/loop", "/ftp"} {
+		resp, err := client.Get(srv.URL + path)
+		if err == nil {
+			_ = resp.Body.Close()
+			t.Errorf("Get(%s) succeeded, want redirect error", path)
+		}
+	}
+}
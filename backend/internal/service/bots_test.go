@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+const (
+	browserUA = "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0"
+	curlUA    = "curl/8.4.0"
+	slackUA   = "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)"
+)
+
+func TestBotsOnClickLimitedLinks(t *testing.T) {
+	type visit struct {
+		ua        string
+		wantURL   bool
+		wantCard  bool
+		wantError string
+	}
+	tests := []struct {
+		name          string
+		visits        []visit
+		wantClicks    int64
+		wantBotClicks int64
+	}{
+		{
+			name:          "cli client is redirected and uses the click",
+			visits:        []visit{{ua: curlUA, wantURL: true}, {ua: browserUA, wantError: "exhausted"}},
+			wantClicks:    0,
+			wantBotClicks: 1,
+		},
+		{
+			name:          "empty user agent is redirected",
+			visits:        []visit{{ua: "", wantURL: true}, {ua: curlUA, wantError: "exhausted"}},
+			wantClicks:    0,
+			wantBotClicks: 1,
+		},
+		{
+			name:          "preview crawler gets a card without consuming the link",
+			visits:        []visit{{ua: slackUA, wantCard: true}, {ua: slackUA, wantCard: true}, {ua: browserUA, wantURL: true}, {ua: slackUA, wantError: "exhausted"}},
+			wantClicks:    1,
+			wantBotClicks: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newMemoryRepo()
+			s := NewLinkService(repo, "http://localhost")
+			defer func() { _ = s.Close(ctx) }()
+
+			if _, err := s.CreateShortLink(ctx, &CreateRequest{CustomCode: "onetime", URL: "https://example.com/file.zip", MaxClicks: 1}); err != nil {
+				t.Fatalf("CreateShortLink: %v", err)
+			}
+			for i, v := range tt.visits {
+				result, err := s.GetLongURL(ctx, &RedirectRequest{Code: "onetime", UserAgent: v.ua})
+				if v.wantError != "" {
+					var serr *ServiceError
+					if !errors.As(err, &serr) || serr.Type != v.wantError {
+						t.Fatalf("visit %d: error = %v, want type %q", i, err, v.wantError)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("visit %d: GetLongURL: %v", i, err)
+				}
+				if v.wantURL && result.LongURL != "https://example.com/file.zip" {
+					t.Errorf("visit %d: LongURL = %q, want redirect", i, result.LongURL)
+				}
+				if v.wantCard && (result.Card == nil || result.Card.TargetURL != "" || result.LongURL != "") {
+					t.Errorf("visit %d: result = %+v, want card without target", i, result)
+				}
+			}
+
+			link, _ := repo.GetByCode(ctx, "onetime")
+			if link.ClickCount != tt.wantClicks || link.BotClickCount != tt.wantBotClicks {
+				t.Errorf("clicks = %d/%d bot, want %d/%d bot", link.ClickCount, link.BotClickCount, tt.wantClicks, tt.wantBotClicks)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"log"
+	"time"
+
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/util"
+)
+
+// newClickEvent 根据重定向请求与结果生成点击事件，访客信息按隐私策略处理；
+// 访客要求不跟踪（DNT / Sec-GPC）时只保留计数所需的字段
+func (s *LinkService) newClickEvent(ctx context.Context, req *RedirectRequest, result *RedirectResult) *model.ClickEvent {
+	event := &model.ClickEvent{
+		Code:           req.Code,
+		Variant:        result.Variant,
+		Timestamp:      time.Now().UTC(),
+		UserAgentClass: util.UserAgentClass(req.UserAgent),
+	}
+	if s.botClassifier.IsBot(req.UserAgent) {
+		event.Bot = true
+		event.UserAgentClass = model.UAClassBot
+	}
+	if req.DoNotTrack && s.privacy.HonorDoNotTrack {
+		event.NoTrack = true
+		return event
+	}
+
+	event.ReferrerHost = util.ReferrerHost(req.Referrer)
+	event.Browser = util.Browser(req.UserAgent)
+	event.Country = util.NormalizeCountry(req.Country)
+
+	salt, err := s.visitorSaltFor(ctx, event.Timestamp)
+	if err != nil {
+		// 拿不到密钥时宁可不记录访客标识
+		log.Printf("failed to load visitor salt: %v", err)
+		return event
+	}
+	event.IP = s.anonymizeIP(req.ClientIP, salt)
+	event.VisitorID = util.VisitorFingerprint(salt, req.ClientIP, req.UserAgent)
+	return event
+}
+
+// fillUniqueVisitors 填充独立访客数；统计失败不影响查询结果
+func (s *LinkService) fillUniqueVisitors(ctx context.Context, info *LinkInfoResponse) {
+	if s.clicks == nil {
+		return
+	}
+	total, err := s.clicks.UniqueVisitors(ctx, info.Code)
+	if err != nil {
+		log.Printf("failed to count unique visitors of %s: %v", info.Code, err)
+		return
+	}
+	today := truncateToBucket(model.StatsIntervalDay, time.Now())
+	daily, err := s.clicks.UniqueVisitorsBetween(ctx, info.Code, today, today)
+	if err != nil {
+		log.Printf("failed to count unique visitors of %s: %v", info.Code, err)
+		return
+	}
+	info.UniqueVisitors = total
+	info.UniqueVisitorsToday = daily
+}
+
+// randomSalt 生成随机的访客指纹密钥（未配置 VISITOR_SALT 时使用，重启后独立访客重新计数）
+func randomSalt() []byte {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+	return salt
+}
+
+// handleClick 由点击管道的 worker 调用：保存原始点击事件（点击次数已在重定向时累加）
+func (s *LinkService) handleClick(ctx context.Context, event *model.ClickEvent) {
+	if s.clicks != nil {
+		if err := s.clicks.RecordClick(ctx, event); err != nil {
+			log.Printf("failed to record click event of %s: %v", event.Code, err)
+		}
+	}
+}
+
+// SubscribeClicks 订阅单个短链接的实时点击事件，受密码保护的链接需提供正确的密码；
+// 调用方结束时必须调用返回的取消函数
+func (s *LinkService) SubscribeClicks(ctx context.Context, code, password string) (<-chan *model.ClickEvent, func(), error) {
+	if s.liveHub == nil {
+		return nil, nil, errLiveStreamDisabled
+	}
+	if err := s.AuthorizeClickData(ctx, code, password); err != nil {
+		return nil, nil, err
+	}
+
+	events, cancel := s.liveHub.Subscribe(code)
+	return events, cancel, nil
+}
+
+// AuthorizeClickData 校验调用方能否读取短链接的点击明细（实时点击流、导出）：
+// 受密码保护的链接需提供正确的密码，未设置密码的链接不做限制。管理命令直接导出，不经过此检查
+func (s *LinkService) AuthorizeClickData(ctx context.Context, code, password string) error {
+	link, err := s.repo.GetByCode(ctx, code)
+	if err != nil {
+		return &ServiceError{Type: "internal_error", Message: "failed to query link"}
+	}
+	if link == nil {
+		return errLinkNotFound
+	}
+	if link.PasswordHash == "" {
+		return nil
+	}
+	if password == "" {
+		return errPasswordRequired
+	}
+	return s.verifyPassword(ctx, link, password)
+}
+
+// SubscribeAllClicks 订阅所有短链接的实时点击事件。全局点击流包含所有链接的来源与地区，
+// 只对持有 LIVE_STREAM_TOKEN 的调用方开放，未配置令牌时不提供
+func (s *LinkService) SubscribeAllClicks(ctx context.Context, token string) (<-chan *model.ClickEvent, func(), error) {
+	if s.liveHub == nil || s.liveStreamToken == "" {
+		return nil, nil, errLiveStreamDisabled
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(s.liveStreamToken)) != 1 {
+		return nil, nil, &ServiceError{Type: "unauthorized", Message: "invalid live stream token"}
+	}
+
+	events, cancel := s.liveHub.Subscribe("")
+	return events, cancel, nil
+}
+
+// Close 停止接收点击事件，并等待已入队的事件与进行中的元数据抓取处理完毕
+func (s *LinkService) Close(ctx context.Context) error {
+	err := s.clickPipeline.Close(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		s.metadataWG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if err == nil {
+			err = ctx.Err()
+		}
+	}
+	return err
+}
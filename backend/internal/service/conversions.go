@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"math"
+	"net/url"
+	"time"
+
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/storage"
+	"url-shortener/backend/internal/util"
+)
+
+const (
+	// AttributionParam 跳转时附加到目标地址上的归因令牌参数名
+	AttributionParam = "sl_ref"
+	// defaultAttributionWindow 默认归因窗口
+	defaultAttributionWindow = 30 * 24 * time.Hour
+	// defaultConversionGoal 未指定目标时使用的目标名
+	defaultConversionGoal = "conversion"
+	// attributionTokenBytes 归因令牌的随机字节数（十六进制编码后为 32 个字符）
+	attributionTokenBytes = 16
+)
+
+// ConversionRequest 目标站点上报的转化（像素请求使用 query 参数，接口请求使用 JSON）
+type ConversionRequest struct {
+	Token string `json:"token" form:"token"`
+	// Goal 转化目标名（如 signup、purchase），省略时为 conversion
+	Goal string `json:"goal,omitempty" form:"goal"`
+	// Value 转化金额，按目标累计
+	Value float64 `json:"value,omitempty" form:"value"`
+}
+
+// ConversionResponse 转化上报结果，Recorded 为 false 表示该令牌的该目标此前已记录过
+type ConversionResponse struct {
+	Code     string `json:"code"`
+	Goal     string `json:"goal"`
+	Recorded bool   `json:"recorded"`
+}
+
+// attachAttribution 签发归因令牌并附加到跳转地址上
+func (s *LinkService) attachAttribution(ctx context.Context, result *RedirectResult, event *model.ClickEvent) error {
+	raw := make([]byte, attributionTokenBytes)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(raw)
+
+	longURL, err := appendQueryParam(result.LongURL, AttributionParam, token)
+	if err != nil {
+		return err
+	}
+	attr := &model.Attribution{
+		Token:     token,
+		Code:      event.Code,
+		Variant:   event.Variant,
+		ClickedAt: event.Timestamp,
+	}
+	if err := s.clicks.CreateAttribution(ctx, attr, s.attributionWindow); err != nil {
+		return err
+	}
+
+	result.LongURL = longURL
+	result.AttributionToken = token
+	result.AttributionTTL = s.attributionWindow
+	return nil
+}
+
+// appendQueryParam 在 URL 的查询串末尾追加参数，保留原有参数的顺序与编码
+func appendQueryParam(rawURL, key, value string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	param := url.QueryEscape(key) + "=" + url.QueryEscape(value)
+	if u.RawQuery == "" {
+		u.RawQuery = param
+	} else {
+		u.RawQuery += "&" + param
+	}
+	return u.String(), nil
+}
+
+// TrackConversion 记录一次转化，并归因到签发令牌的短链接
+func (s *LinkService) TrackConversion(ctx context.Context, req *ConversionRequest) (*ConversionResponse, error) {
+	if s.clicks == nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "conversion tracking is not enabled"}
+	}
+	if !validAttributionToken(req.Token) {
+		return nil, &ServiceError{Type: "invalid_request", Message: "token is missing or malformed"}
+	}
+	goal := req.Goal
+	if goal == "" {
+		goal = defaultConversionGoal
+	}
+	if err := util.ValidateGoal(goal); err != nil {
+		return nil, &ServiceError{Type: "invalid_request", Message: err.Error()}
+	}
+	if req.Value < 0 || math.IsNaN(req.Value) || math.IsInf(req.Value, 0) {
+		return nil, &ServiceError{Type: "invalid_request", Message: "value must be a non-negative number"}
+	}
+
+	conv := &model.Conversion{
+		Token:     req.Token,
+		Goal:      goal,
+		Value:     req.Value,
+		Timestamp: time.Now().UTC(),
+	}
+	recorded, err := s.clicks.RecordConversion(ctx, conv)
+	if err != nil {
+		if errors.Is(err, storage.ErrAttributionNotFound) {
+			return nil, &ServiceError{Type: "not_found", Message: "attribution token not found or expired"}
+		}
+		if errors.Is(err, storage.ErrTooManyGoals) {
+			return nil, &ServiceError{Type: "invalid_request", Message: "attribution token has reached its goal limit"}
+		}
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to record conversion"}
+	}
+	return &ConversionResponse{Code: conv.Code, Goal: goal, Recorded: recorded}, nil
+}
+
+// validAttributionToken 令牌必须是 attachAttribution 生成的格式，避免任意字符串进入 Redis key
+func validAttributionToken(token string) bool {
+	if len(token) != attributionTokenBytes*2 {
+		return false
+	}
+	_, err := hex.DecodeString(token)
+	return err == nil
+}
+
+// fillConversions 填充转化次数；统计失败不影响查询结果
+func (s *LinkService) fillConversions(ctx context.Context, info *LinkInfoResponse) {
+	if s.clicks == nil {
+		return
+	}
+	total, goals, err := s.clicks.ConversionTotals(ctx, info.Code)
+	if err != nil {
+		log.Printf("failed to count conversions of %s: %v", info.Code, err)
+		return
+	}
+	info.ConversionCount = total
+	if len(goals) > 0 {
+		info.ConversionGoals = goals
+	}
+}
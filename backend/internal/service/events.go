@@ -0,0 +1,47 @@
+package service
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"url-shortener/backend/internal/model"
+)
+
+// newDomainEvent 生成领域事件，交给存储层与触发它的写操作一起写入 outbox；
+// data 编码失败时事件仍然写入，只是不带内容
+func newDomainEvent(eventType, code string, data any) *model.DomainEvent {
+	event := &model.DomainEvent{
+		Type:       eventType,
+		Code:       code,
+		OccurredAt: time.Now().UTC(),
+	}
+	if data != nil {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("failed to encode %s event of %s: %v", eventType, code, err)
+		} else {
+			event.Data = raw
+		}
+	}
+	return event
+}
+
+// linkEvent 以完整的链接信息作为内容的事件（link.created / updated / deleted / expired），
+// 下游（搜索索引、数据仓库）需要目标地址，因此不做脱敏；未启用领域事件时返回 nil
+func (s *LinkService) linkEvent(eventType string, link *model.ShortLink) []*model.DomainEvent {
+	if !s.domainEvents {
+		return nil
+	}
+	return []*model.DomainEvent{newDomainEvent(eventType, link.Code, newLinkInfo(link, false))}
+}
+
+// clickedEvent link.clicked 事件，内容与实时点击流相同（不含 IP 与访客指纹）；未启用领域事件时返回 nil
+func (s *LinkService) clickedEvent(click *model.ClickEvent) []*model.DomainEvent {
+	if !s.domainEvents {
+		return nil
+	}
+	data := *click
+	data.IP = ""
+	return []*model.DomainEvent{newDomainEvent(model.EventLinkClicked, click.Code, &data)}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/backend/internal/model"
+)
+
+// ExportRequest 导出参数，from/to 格式同统计查询；Interval 仅用于导出统计桶
+type ExportRequest struct {
+	From     string `form:"from"`
+	To       string `form:"to"`
+	Interval string `form:"interval"`
+	Format   string `form:"format"`
+}
+
+// ExportClicks 按时间顺序把 [from, to] 内的原始点击事件逐条交给 fn，不在内存中缓存。
+// 未指定 from 时从最早的事件开始，未指定 to 时截止到当前时间
+func (s *LinkService) ExportClicks(ctx context.Context, code string, req *ExportRequest, fn func(event *model.ClickEvent) error) error {
+	if s.clicks == nil {
+		return &ServiceError{Type: "internal_error", Message: "click statistics are not enabled"}
+	}
+
+	var from time.Time
+	if req.From != "" {
+		t, _, err := parseStatsTime(req.From)
+		if err != nil {
+			return &ServiceError{Type: "invalid_request", Message: "from must be RFC3339 or YYYY-MM-DD"}
+		}
+		from = t
+	}
+	to := time.Now().UTC()
+	if req.To != "" {
+		t, dateOnly, err := parseStatsTime(req.To)
+		if err != nil {
+			return &ServiceError{Type: "invalid_request", Message: "to must be RFC3339 or YYYY-MM-DD"}
+		}
+		if dateOnly {
+			t = t.Add(24*time.Hour - time.Nanosecond)
+		}
+		to = t
+	}
+	if from.After(to) {
+		return &ServiceError{Type: "invalid_request", Message: "from must be before to"}
+	}
+
+	link, err := s.repo.GetByCode(ctx, code)
+	if err != nil {
+		return &ServiceError{Type: "internal_error", Message: "failed to query link"}
+	}
+	if link == nil {
+		return errLinkNotFound
+	}
+
+	return s.clicks.ForEachClick(ctx, code, from, to, fn)
+}
+
+// ExportStats 返回用于导出的统计桶，参数与 GetClickStats 相同
+func (s *LinkService) ExportStats(ctx context.Context, code string, req *ExportRequest) (*StatsResponse, error) {
+	return s.GetClickStats(ctx, code, &StatsRequest{
+		From:     req.From,
+		To:       req.To,
+		Interval: req.Interval,
+	})
+}
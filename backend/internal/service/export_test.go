@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAuthorizeClickData(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepo()
+	s := NewLinkService(repo, "http://localhost")
+	defer func() { _ = s.Close(ctx) }()
+
+	for _, req := range []*CreateRequest{
+		{CustomCode: "public", URL: "https://example.com"},
+		{CustomCode: "locked", URL: "https://example.com", Password: "s3cret-pass"},
+	} {
+		if _, err := s.CreateShortLink(ctx, req); err != nil {
+			t.Fatalf("CreateShortLink(%s): %v", req.CustomCode, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		password string
+		wantType string
+	}{
+		{"public link", "public", "", ""},
+		{"protected link without password", "locked", "", "password_required"},
+		{"protected link with wrong password", "locked", "wrong-pass", "invalid_password"},
+		{"protected link with password", "locked", "s3cret-pass", ""},
+		{"missing link", "missing", "", "not_found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.AuthorizeClickData(ctx, tt.code, tt.password)
+			if tt.wantType == "" {
+				if err != nil {
+					t.Fatalf("AuthorizeClickData: %v", err)
+				}
+				return
+			}
+			var serr *ServiceError
+			if !errors.As(err, &serr) || serr.Type != tt.wantType {
+				t.Fatalf("AuthorizeClickData error = %v, want type %q", err, tt.wantType)
+			}
+		})
+	}
+}
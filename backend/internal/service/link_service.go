@@ -3,8 +3,15 @@ package service
 import (
 	"context"
 	"errors"
+	"log"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
+	"url-shortener/backend/internal/analytics"
+	"url-shortener/backend/internal/botdetect"
 	"url-shortener/backend/internal/model"
 	"url-shortener/backend/internal/storage"
 	"url-shortener/backend/internal/util"
@@ -14,20 +21,208 @@ type LinkService struct {
 	repo    storage.LinkRepository
 	baseURL string
 	codeGen func(int64) string
+	// fallbackURL 全局备用地址：链接不存在或不可用且未配置自身备用地址时跳转
+	fallbackURL string
+	// metadataFetcher 创建后异步抓取目标页面元数据（为空则不抓取），metadataSem 限制并发抓取数，
+	// metadataWG 跟踪进行中的抓取，Close 时等待其结束
+	metadataFetcher MetadataFetcher
+	metadataSem     chan struct{}
+	metadataWG      sync.WaitGroup
+	// clicks 保存原始点击事件（为空则只累加点击次数），clickPipeline 异步处理点击
+	clicks storage.ClickRepository
+	// domainEvents 为 true 时写操作同时把领域事件写入 outbox（配置了事件 sink 时启用）
+	domainEvents bool
+	// liveHub 向 SSE 连接分发实时点击事件（为空则不支持实时订阅），
+	// liveStreamToken 为订阅全局点击流所需的令牌（为空则不开放全局点击流）
+	liveHub         *analytics.Hub
+	liveStreamToken string
+	// botClassifier 识别机器人访问，机器人单独计数（同样占用 max_clicks）
+	botClassifier BotClassifier
+	// visitorSalt 计算访客指纹的密钥，指纹只用于独立访客计数
+	visitorSalt []byte
+	salts       saltCache
+	// privacy 访客数据处理策略（IP 匿名化、DNT、原始事件保留时长）
+	privacy       PrivacyPolicy
+	pipelineOpts  analytics.Options
+	clickPipeline *analytics.Pipeline
+	// attributionWindow 归因令牌的有效期，超过后上报的转化不再计入
+	attributionWindow time.Duration
+	// webhooks 保存 webhook 与投递队列（为空则不支持 webhook），thresholds 缓存各短码的点击阈值
+	webhooks   storage.WebhookRepository
+	thresholds thresholdCache
 }
 
-func NewLinkService(repo storage.LinkRepository, baseURL string) *LinkService {
-	return &LinkService{
+// MetadataFetcher 抓取目标页面元数据
+type MetadataFetcher interface {
+	Fetch(ctx context.Context, rawURL string) (*model.PageMetadata, error)
+}
+
+// BotClassifier 根据 User-Agent 判断访问是否来自机器人
+type BotClassifier interface {
+	IsBot(userAgent string) bool
+}
+
+const (
+	metadataFetchConcurrency = 8
+	metadataFetchTimeout     = 15 * time.Second
+)
+
+// Option 配置 LinkService 的可选项
+type Option func(*LinkService)
+
+// WithFallbackURL 设置全局备用地址
+func WithFallbackURL(fallbackURL string) Option {
+	return func(s *LinkService) {
+		s.fallbackURL = fallbackURL
+	}
+}
+
+// WithMetadataFetcher 启用创建后异步抓取目标页面元数据
+func WithMetadataFetcher(fetcher MetadataFetcher) Option {
+	return func(s *LinkService) {
+		s.metadataFetcher = fetcher
+		s.metadataSem = make(chan struct{}, metadataFetchConcurrency)
+	}
+}
+
+// WithDomainEvents 启用领域事件：写操作同时把事件写入 outbox，由 events.Relay 投递
+func WithDomainEvents() Option {
+	return func(s *LinkService) {
+		s.domainEvents = true
+	}
+}
+
+// WithLiveStreamToken 设置订阅全局实时点击流所需的令牌
+func WithLiveStreamToken(token string) Option {
+	return func(s *LinkService) {
+		s.liveStreamToken = token
+	}
+}
+
+// WithClickRecorder 启用原始点击事件记录
+func WithClickRecorder(clicks storage.ClickRepository) Option {
+	return func(s *LinkService) {
+		s.clicks = clicks
+	}
+}
+
+// WithLiveHub 启用实时点击订阅
+func WithLiveHub(hub *analytics.Hub) Option {
+	return func(s *LinkService) {
+		s.liveHub = hub
+	}
+}
+
+// WithBotClassifier 设置机器人识别规则（默认使用 botdetect 内置特征）
+func WithBotClassifier(classifier BotClassifier) Option {
+	return func(s *LinkService) {
+		s.botClassifier = classifier
+	}
+}
+
+// WithVisitorSalt 设置访客指纹密钥（多实例部署需保持一致，否则独立访客会重复计数）
+func WithVisitorSalt(salt string) Option {
+	return func(s *LinkService) {
+		s.visitorSalt = []byte(salt)
+	}
+}
+
+// WithAttributionWindow 设置转化归因窗口（默认 30 天）
+func WithAttributionWindow(window time.Duration) Option {
+	return func(s *LinkService) {
+		s.attributionWindow = window
+	}
+}
+
+// WithWebhooks 启用 webhook 推送（由 webhook.Dispatcher 从队列中投递）
+func WithWebhooks(webhooks storage.WebhookRepository) Option {
+	return func(s *LinkService) {
+		s.webhooks = webhooks
+	}
+}
+
+// WithClickPipeline 设置点击管道的缓冲长度与 worker 数
+func WithClickPipeline(opts analytics.Options) Option {
+	return func(s *LinkService) {
+		s.pipelineOpts = opts
+	}
+}
+
+func NewLinkService(repo storage.LinkRepository, baseURL string, opts ...Option) *LinkService {
+	s := &LinkService{
 		repo:    repo,
 		baseURL: baseURL,
 		codeGen: util.GenerateCodeFromID,
+		privacy: DefaultPrivacyPolicy(),
+
+		attributionWindow: defaultAttributionWindow,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	if s.botClassifier == nil {
+		s.botClassifier, _ = botdetect.NewClassifier("")
+	}
+	if len(s.visitorSalt) == 0 {
+		s.visitorSalt = randomSalt()
+	}
+	s.clickPipeline = analytics.NewPipeline(s.handleClick, s.pipelineOpts)
+	return s
 }
 
 type CreateRequest struct {
-	URL        string     `json:"url" binding:"required"`
-	CustomCode string     `json:"custom_code,omitempty"`
-	ExpireAt   *time.Time `json:"expire_at,omitempty"`
+	URL          string               `json:"url"`
+	CustomCode   string               `json:"custom_code,omitempty"`
+	ExpireAt     *time.Time           `json:"expire_at,omitempty"`
+	Destinations []DestinationRequest `json:"destinations,omitempty"`
+	// RoutingMode 多目标选择方式：weighted（默认）、round_robin、failover
+	RoutingMode  string            `json:"routing_mode,omitempty"`
+	Sticky       bool              `json:"sticky,omitempty"`
+	LanguageURLs map[string]string `json:"language_urls,omitempty"`
+	// ActivateAt/Schedule 控制生效时间，未生效时返回 InactiveMessage
+	// FallbackURL 为链接不可用（未生效、过期、停用、点击用完）时跳转的备用地址
+	ActivateAt      *time.Time      `json:"activate_at,omitempty"`
+	Schedule        *model.Schedule `json:"schedule,omitempty"`
+	FallbackURL     string          `json:"fallback_url,omitempty"`
+	InactiveMessage string          `json:"inactive_message,omitempty"`
+	// MaxClicks 最大点击次数（1 即一次性链接），0 表示不限制
+	MaxClicks int64 `json:"max_clicks,omitempty"`
+	// Password 访问密码，仅保存其加盐哈希
+	Password string `json:"password,omitempty"`
+	// ForcePreview 强制所有访问者先经过预览页
+	ForcePreview bool `json:"force_preview,omitempty"`
+	// OGTitle/OGDescription/OGImage 社交平台抓取时展示的卡片信息
+	OGTitle       string `json:"og_title,omitempty"`
+	OGDescription string `json:"og_description,omitempty"`
+	OGImage       string `json:"og_image,omitempty"`
+	// AppURL App 深度链接（自定义 scheme），url 作为网页回退地址
+	AppURL          string `json:"app_url,omitempty"`
+	IOSStoreURL     string `json:"ios_store_url,omitempty"`
+	AndroidStoreURL string `json:"android_store_url,omitempty"`
+	// HideReferrer 跳转时不向目标站点发送来源页面
+	HideReferrer bool `json:"hide_referrer,omitempty"`
+	// TrackConversions 跳转时附带归因令牌，供目标站点上报转化
+	TrackConversions bool `json:"track_conversions,omitempty"`
+	// Webhooks 创建时同时注册的 webhook（可收到 link.created）
+	Webhooks []WebhookRequest `json:"webhooks,omitempty"`
+}
+
+// UpdateRequest 修改短链接的参数，省略的字段保持不变
+type UpdateRequest struct {
+	URL *string `json:"url,omitempty"`
+	// ExpireAt 新的过期时间，ClearExpireAt 为 true 时改为永不过期
+	ExpireAt        *time.Time `json:"expire_at,omitempty"`
+	ClearExpireAt   bool       `json:"clear_expire_at,omitempty"`
+	FallbackURL     *string    `json:"fallback_url,omitempty"`
+	InactiveMessage *string    `json:"inactive_message,omitempty"`
+	MaxClicks       *int64     `json:"max_clicks,omitempty"`
+}
+
+// DestinationRequest A/B 分流中的一个目标地址，weight 省略时默认为 1
+type DestinationRequest struct {
+	ID     string `json:"id,omitempty"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight,omitempty"`
 }
 
 type CreateResponse struct {
@@ -35,26 +230,186 @@ type CreateResponse struct {
 	ShortURL string     `json:"short_url"`
 	LongURL  string     `json:"long_url"`
 	ExpireAt *time.Time `json:"expire_at,omitempty"`
+	// Webhooks 创建时注册的 webhook，包含只返回这一次的签名密钥
+	Webhooks []*WebhookResponse `json:"webhooks,omitempty"`
 }
 
 type LinkInfoResponse struct {
-	Code           string     `json:"code"`
-	LongURL        string     `json:"long_url"`
-	CreatedAt      time.Time  `json:"created_at"`
-	ExpireAt       *time.Time `json:"expire_at,omitempty"`
-	ClickCount     int64      `json:"click_count"`
-	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty"`
+	Code       string     `json:"code"`
+	LongURL    string     `json:"long_url"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpireAt   *time.Time `json:"expire_at,omitempty"`
+	ClickCount int64      `json:"click_count"`
+	// BotClickCount 为机器人访问次数，不计入 click_count
+	BotClickCount int64 `json:"bot_click_count"`
+	// UniqueVisitors / UniqueVisitorsToday 为 HyperLogLog 估算的独立访客数（总计 / 当天 UTC）
+	UniqueVisitors      int64             `json:"unique_visitors"`
+	UniqueVisitorsToday int64             `json:"unique_visitors_today"`
+	LastAccessedAt      *time.Time        `json:"last_accessed_at,omitempty"`
+	Destinations        []DestinationInfo `json:"destinations,omitempty"`
+	RoutingMode         string            `json:"routing_mode,omitempty"`
+	Sticky              bool              `json:"sticky,omitempty"`
+	LanguageURLs        map[string]string `json:"language_urls,omitempty"`
+	ActivateAt          *time.Time        `json:"activate_at,omitempty"`
+	Schedule            *model.Schedule   `json:"schedule,omitempty"`
+	FallbackURL         string            `json:"fallback_url,omitempty"`
+	MaxClicks           int64             `json:"max_clicks,omitempty"`
+	// PasswordProtected 为 true 且调用方未提供正确密码时，不返回 long_url 等目标地址
+	PasswordProtected bool   `json:"password_protected,omitempty"`
+	ForcePreview      bool   `json:"force_preview,omitempty"`
+	Disabled          bool   `json:"disabled,omitempty"`
+	OGTitle           string `json:"og_title,omitempty"`
+	OGDescription     string `json:"og_description,omitempty"`
+	OGImage           string `json:"og_image,omitempty"`
+	AppURL            string `json:"app_url,omitempty"`
+	IOSStoreURL       string `json:"ios_store_url,omitempty"`
+	AndroidStoreURL   string `json:"android_store_url,omitempty"`
+	HideReferrer      bool   `json:"hide_referrer,omitempty"`
+	TrackConversions  bool   `json:"track_conversions,omitempty"`
+	// ConversionCount 为归因到该链接的转化次数，ConversionGoals 为按目标划分的次数
+	ConversionCount int64            `json:"conversion_count"`
+	ConversionGoals map[string]int64 `json:"conversion_goals,omitempty"`
+	// Metadata 异步抓取的目标页面信息（标题、描述、图片、最终地址）
+	Metadata *model.PageMetadata `json:"metadata,omitempty"`
+	// Health 主链接（long_url）最近一次健康检查结果
+	Health *model.HealthStatus `json:"health,omitempty"`
+	// LanguageHealth 语言路由各目标地址最近一次健康检查结果，键为语言标签
+	LanguageHealth map[string]*model.HealthStatus `json:"language_health,omitempty"`
+}
+
+// DestinationInfo 目标分组信息、点击次数及健康检查结果
+type DestinationInfo struct {
+	ID         string              `json:"id"`
+	URL        string              `json:"url"`
+	Weight     int                 `json:"weight"`
+	ClickCount int64               `json:"click_count"`
+	Health     *model.HealthStatus `json:"health,omitempty"`
+}
+
+// RedirectRequest 重定向时携带的访问者信息
+type RedirectRequest struct {
+	Code string
+	// Variant 为访问者 cookie 中记录的 A/B 分组，用于粘性分流
+	Variant string
+	// AcceptLanguage 为请求的 Accept-Language 头，用于按语言路由
+	AcceptLanguage string
+	// Password 为访问者提交的密码（仅用于受密码保护的链接）
+	Password string
+	// Confirmed 为 true 表示访问者已在预览页确认继续
+	Confirmed bool
+	// UserAgent 为请求的 User-Agent，用于识别社交平台抓取器
+	UserAgent string
+	// Referrer 为请求的 Referer 头，ClientIP 为访问者 IP，Country 为代理提供的国家代码，仅用于点击统计
+	Referrer string
+	ClientIP string
+	Country  string
+	// DoNotTrack 为 true 表示访客发送了 DNT: 1 或 Sec-GPC: 1
+	DoNotTrack bool
+}
+
+// RedirectResult 重定向目标
+type RedirectResult struct {
+	LongURL string
+	// Variant 为本次命中的 A/B 分组（单目标链接为空）
+	Variant string
+	// Sticky 为 true 时 handler 需要把 Variant 写入 cookie
+	Sticky bool
+	// Language 为按 Accept-Language 命中的语言标签
+	Language string
+	// LanguageRouted 为 true 时表示结果依赖 Accept-Language（需设置 Vary 头）
+	LanguageRouted bool
+	// Card 非空时表示请求来自社交平台抓取器，应返回卡片页而非 302
+	Card *SocialCard
+	// DeepLink 非空时表示移动端访问带 App 深度链接的短链接，应返回尝试打开 App 的页面
+	DeepLink *DeepLink
+	// HideReferrer 为 true 时应通过 no-referrer 的 meta refresh 页面跳转，而非 302
+	HideReferrer bool
+	// AttributionToken 非空时 handler 需要把归因令牌写入 cookie（LongURL 已附带 sl_ref 参数），
+	// AttributionTTL 为 cookie 的有效期
+	AttributionToken string
+	AttributionTTL   time.Duration
 }
 
+// DeepLink 移动端尝试打开 App 所需的信息，打开失败时回退到 RedirectResult.LongURL
+type DeepLink struct {
+	AppURL string
+	// StoreURL 为访问者所在平台的应用商店地址（可为空）
+	StoreURL string
+}
+
+// SocialCard 社交平台抓取短链接时展示的卡片信息
+type SocialCard struct {
+	Title       string
+	Description string
+	Image       string
+	// ShortURL 为短链接地址（作为 og:url）
+	ShortURL string
+	// TargetURL 为卡片页中的跳转地址；受密码保护、强制预览或限制点击次数的链接为空，避免泄露目标地址
+	TargetURL string
+}
+
+var (
+	// errLinkNotFound 短码不存在
+	errLinkNotFound = &ServiceError{Type: "not_found", Message: "short link not found"}
+	// errLinkExpired 链接已过期
+	errLinkExpired = &ServiceError{Type: "expired", Message: "short link has expired"}
+	// errLinkDisabled 链接已被停用
+	errLinkDisabled = &ServiceError{Type: "disabled", Message: "short link has been disabled"}
+	// errClicksExhausted 点击次数已用完
+	errClicksExhausted = &ServiceError{Type: "exhausted", Message: "short link has reached its maximum number of clicks"}
+	// errPasswordRequired 链接受密码保护，需要先提交密码
+	errPasswordRequired = &ServiceError{Type: "password_required", Message: "short link is password protected"}
+	// errInvalidPassword 提交的密码错误
+	errInvalidPassword = &ServiceError{Type: "invalid_password", Message: "incorrect password"}
+	// errPreviewRequired 链接要求访问者先查看预览页
+	errPreviewRequired = &ServiceError{Type: "preview_required", Message: "short link requires preview confirmation"}
+	// errTooManyAttempts 密码尝试次数过多
+	errTooManyAttempts = &ServiceError{Type: "too_many_attempts", Message: "too many password attempts, please try again later"}
+	// errWebhookNotFound webhook 不存在或不属于该短码
+	errWebhookNotFound = &ServiceError{Type: "not_found", Message: "webhook not found"}
+	// errWebhooksDisabled 未启用 webhook
+	errWebhooksDisabled = &ServiceError{Type: "internal_error", Message: "webhooks are not enabled"}
+	// errManageForbidden 未设置访问密码的链接没有可验证的管理凭证，不能通过 API 修改
+	errManageForbidden = &ServiceError{Type: "forbidden", Message: "only password protected links can be managed; set a password when creating the link"}
+	// errLiveStreamDisabled 未启用实时点击流（全局点击流还需配置令牌）
+	errLiveStreamDisabled = &ServiceError{Type: "not_found", Message: "live click stream is not enabled"}
+)
+
+const (
+	maxDestinations = 10
+	maxLanguageURLs = 50
+
+	// 每个短码在窗口内允许的密码尝试次数
+	passwordAttemptLimit  = 10
+	passwordAttemptWindow = 15 * time.Minute
+)
+
 // CreateShortLink 创建短链接
 func (s *LinkService) CreateShortLink(ctx context.Context, req *CreateRequest) (*CreateResponse, error) {
+	// 验证 A/B 分流目标
+	destinations, err := buildDestinations(req.Destinations)
+	if err != nil {
+		return nil, &ServiceError{Type: "invalid_request", Message: err.Error()}
+	}
+	switch req.RoutingMode {
+	case "", model.RoutingWeighted, model.RoutingRoundRobin, model.RoutingFailover:
+	default:
+		return nil, &ServiceError{Type: "invalid_request", Message: "routing_mode must be weighted, round_robin or failover"}
+	}
+	if err := validateLanguageURLs(req.LanguageURLs); err != nil {
+		return nil, &ServiceError{Type: "invalid_request", Message: err.Error()}
+	}
+	// 只提供 destinations 时，以第一个分组作为主链接
+	if req.URL == "" && len(destinations) > 0 {
+		req.URL = destinations[0].URL
+	}
+
 	// 验证 URL
 	if err := util.ValidateURL(req.URL); err != nil {
 		return nil, &ServiceError{Type: "invalid_request", Message: err.Error()}
 	}
 
 	var code string
-	var err error
 
 	if req.CustomCode != "" {
 		// 使用自定义短码
@@ -83,17 +438,90 @@ func (s *LinkService) CreateShortLink(ctx context.Context, req *CreateRequest) (
 		return nil, &ServiceError{Type: "invalid_request", Message: "expire_at must be in the future"}
 	}
 
+	// 检查生效时间与周期窗口是否有效
+	if req.ActivateAt != nil && req.ExpireAt != nil && !req.ActivateAt.Before(*req.ExpireAt) {
+		return nil, &ServiceError{Type: "invalid_request", Message: util.ErrActivateAfterExpire.Error()}
+	}
+	if err := validateSchedule(req.Schedule); err != nil {
+		return nil, &ServiceError{Type: "invalid_request", Message: err.Error()}
+	}
+	if req.FallbackURL != "" {
+		if err := util.ValidateURL(req.FallbackURL); err != nil {
+			return nil, &ServiceError{Type: "invalid_request", Message: "fallback_url: " + err.Error()}
+		}
+	}
+	if err := validateDeepLink(req); err != nil {
+		return nil, &ServiceError{Type: "invalid_request", Message: err.Error()}
+	}
+	if err := validateSocialCard(req); err != nil {
+		return nil, &ServiceError{Type: "invalid_request", Message: err.Error()}
+	}
+	if len(req.InactiveMessage) > 256 {
+		return nil, &ServiceError{Type: "invalid_request", Message: "inactive_message exceeds maximum length of 256 characters"}
+	}
+	if req.MaxClicks < 0 {
+		return nil, &ServiceError{Type: "invalid_request", Message: "max_clicks must not be negative"}
+	}
+	if len(req.Webhooks) > 0 && s.webhooks == nil {
+		return nil, errWebhooksDisabled
+	}
+	// webhook 只能凭链接密码查看与删除，未设置密码时注册后将无法管理
+	if len(req.Webhooks) > 0 && req.Password == "" {
+		return nil, &ServiceError{Type: "invalid_request", Message: "webhooks require a password so they can be managed later"}
+	}
+	if len(req.Webhooks) > maxWebhooksPerLink {
+		return nil, &ServiceError{Type: "invalid_request", Message: "webhooks exceed maximum of 10 per link"}
+	}
+	hooks := make([]*model.Webhook, 0, len(req.Webhooks))
+	for i := range req.Webhooks {
+		hook, err := buildWebhook(code, &req.Webhooks[i])
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+
+	var passwordHash string
+	if req.Password != "" {
+		if err := util.ValidatePassword(req.Password); err != nil {
+			return nil, &ServiceError{Type: "invalid_request", Message: err.Error()}
+		}
+		passwordHash, err = util.HashPassword(req.Password)
+		if err != nil {
+			return nil, &ServiceError{Type: "internal_error", Message: "failed to hash password"}
+		}
+	}
+
 	link := &model.ShortLink{
-		ID:        0,
-		Code:      code,
-		LongURL:   req.URL,
-		CreatedAt: time.Now().UTC(),
-		ExpireAt:  req.ExpireAt,
+		ID:               0,
+		Code:             code,
+		LongURL:          req.URL,
+		CreatedAt:        time.Now().UTC(),
+		ExpireAt:         req.ExpireAt,
+		Destinations:     destinations,
+		RoutingMode:      req.RoutingMode,
+		StickyVariant:    req.Sticky && len(destinations) > 0,
+		LanguageURLs:     req.LanguageURLs,
+		ActivateAt:       req.ActivateAt,
+		Schedule:         req.Schedule,
+		FallbackURL:      req.FallbackURL,
+		InactiveMessage:  req.InactiveMessage,
+		MaxClicks:        req.MaxClicks,
+		PasswordHash:     passwordHash,
+		ForcePreview:     req.ForcePreview,
+		OGTitle:          req.OGTitle,
+		OGDescription:    req.OGDescription,
+		OGImage:          req.OGImage,
+		AppURL:           req.AppURL,
+		IOSStoreURL:      req.IOSStoreURL,
+		AndroidStoreURL:  req.AndroidStoreURL,
+		HideReferrer:     req.HideReferrer,
+		TrackConversions: req.TrackConversions,
 	}
 
 	// 随机生成的短码不需要设置 ID（Redis 存储中 ID 字段可选）
 
-	created, err := s.repo.Create(ctx, link)
+	created, err := s.repo.Create(ctx, link, s.linkEvent(model.EventLinkCreated, link)...)
 	if err != nil {
 		// Redis 场景下：只要写入失败且短码已存在，统一返回冲突（更友好）
 		existing, _ := s.repo.GetByCode(ctx, code)
@@ -103,40 +531,168 @@ func (s *LinkService) CreateShortLink(ctx context.Context, req *CreateRequest) (
 		return nil, &ServiceError{Type: "internal_error", Message: "failed to create short link"}
 	}
 
+	var registered []*WebhookResponse
+	for _, hook := range hooks {
+		if err := s.webhooks.CreateWebhook(ctx, hook); err != nil {
+			// webhook 写入失败时撤销整个创建，避免调用方收到错误而链接已经存在
+			s.rollbackCreate(ctx, created)
+			return nil, &ServiceError{Type: "internal_error", Message: "failed to create webhook"}
+		}
+		registered = append(registered, &WebhookResponse{Webhook: hook, Secret: hook.Secret})
+	}
+	if len(hooks) > 0 {
+		s.thresholds.invalidate(created.Code)
+	}
+
+	// 异步抓取目标页面元数据，不阻塞创建
+	if s.metadataFetcher != nil {
+		s.startMetadataFetch(created.Code, created.LongURL)
+	}
+	s.notifyWebhooks(ctx, created, model.WebhookEventCreated)
+
 	shortURL := s.baseURL + "/" + created.Code
 	return &CreateResponse{
 		Code:     created.Code,
 		ShortURL: shortURL,
 		LongURL:  created.LongURL,
 		ExpireAt: created.ExpireAt,
+		Webhooks: registered,
 	}, nil
 }
 
-// GetLongURL 根据短码获取长链接（用于重定向）
-func (s *LinkService) GetLongURL(ctx context.Context, code string) (string, error) {
-	link, err := s.repo.GetByCode(ctx, code)
+// rollbackCreate 撤销刚创建的链接及已注册的 webhook；outbox 中已写入的 link.created 由 link.deleted 抵消
+func (s *LinkService) rollbackCreate(ctx context.Context, link *model.ShortLink) {
+	if err := s.webhooks.DeleteWebhooks(ctx, link.Code); err != nil {
+		log.Printf("failed to roll back webhooks of %s: %v", link.Code, err)
+	}
+	if err := s.repo.Delete(ctx, link.Code, s.linkEvent(model.EventLinkDeleted, link)...); err != nil {
+		log.Printf("failed to roll back link %s: %v", link.Code, err)
+	}
+}
+
+// GetLongURL 根据短码获取长链接（用于重定向），多目标链接按权重选择一个分组
+func (s *LinkService) GetLongURL(ctx context.Context, req *RedirectRequest) (*RedirectResult, error) {
+	link, err := s.repo.GetByCode(ctx, req.Code)
 	if err != nil {
-		return "", &ServiceError{Type: "internal_error", Message: "failed to query link"}
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to query link"}
 	}
 	if link == nil {
-		return "", &ServiceError{Type: "not_found", Message: "short link not found"}
+		return s.fallback(nil, errLinkNotFound)
 	}
 
-	// 检查是否过期
+	// 检查是否停用、过期
+	if link.Disabled {
+		return s.fallback(link, errLinkDisabled)
+	}
 	if link.ExpireAt != nil && link.ExpireAt.Before(time.Now()) {
-		return "", &ServiceError{Type: "not_found", Message: "short link expired"}
+		return s.fallback(link, errLinkExpired)
 	}
 
-	// 异步更新点击次数（可选：可以放到 goroutine 中）
-	go func() {
-		_ = s.repo.IncrementClick(context.Background(), code)
-	}()
+	// 检查是否处于生效时间内
+	if message, active := linkActive(link, time.Now()); !active {
+		if link.InactiveMessage != "" {
+			message = link.InactiveMessage
+		}
+		return s.fallback(link, &ServiceError{Type: "not_active", Message: message})
+	}
+
+	if link.MaxClicks > 0 && link.ClickCount+link.BotClickCount >= link.MaxClicks {
+		return s.fallback(link, errClicksExhausted)
+	}
+
+	// 社交平台抓取器获取卡片信息，不跳转也不计入点击。
+	// 限制点击次数的链接即使未配置卡片也只返回不含目标地址的卡片页，避免预览消耗一次性链接
+	if util.IsUnfurlBot(req.UserAgent) && (hasSocialCard(link) || link.MaxClicks > 0) {
+		card := s.socialCard(link)
+		if link.PasswordHash == "" && !link.ForcePreview && link.MaxClicks == 0 {
+			card.TargetURL = link.LongURL
+		}
+		return &RedirectResult{Card: card}, nil
+	}
+
+	// 强制预览的链接需访问者在预览页确认后才跳转
+	if link.ForcePreview && !req.Confirmed {
+		return nil, errPreviewRequired
+	}
 
-	return link.LongURL, nil
+	// 受密码保护的链接需先校验密码
+	if link.PasswordHash != "" {
+		if req.Password == "" {
+			return nil, errPasswordRequired
+		}
+		if err := s.verifyPassword(ctx, link, req.Password); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &RedirectResult{LongURL: link.LongURL, HideReferrer: link.HideReferrer}
+	if len(link.LanguageURLs) > 0 {
+		result.LanguageRouted = true
+		result.Language = matchLanguageURL(link.LanguageURLs, req.AcceptLanguage)
+	}
+	// 语言路由优先；未命中语言时再走 A/B 分流或默认地址
+	if result.Language != "" {
+		result.LongURL = link.LanguageURLs[result.Language]
+	} else if len(link.Destinations) > 0 {
+		dest := s.selectDestination(ctx, link, req.Variant)
+		result.LongURL = dest.URL
+		result.Variant = dest.ID
+		result.Sticky = link.StickyVariant
+	}
+
+	// 移动端访问时先尝试打开 App，桌面端直接跳转网页
+	if link.AppURL != "" {
+		switch util.MobilePlatform(req.UserAgent) {
+		case "ios":
+			result.DeepLink = &DeepLink{AppURL: link.AppURL, StoreURL: link.IOSStoreURL}
+		case "android":
+			result.DeepLink = &DeepLink{AppURL: link.AppURL, StoreURL: link.AndroidStoreURL}
+		}
+	}
+
+	// 点击次数在跳转前同步累加（限制点击次数的链接由 Lua 脚本原子地检查上限），
+	// 点击管道只负责保存原始事件，队列已满或进程崩溃时丢失事件也不影响计数。
+	// 机器人照常跳转，只计入 bot_click_count，但同样占用 max_clicks，伪造 User-Agent 不能绕过次数限制
+	event := s.newClickEvent(ctx, req, result)
+	var count int64
+	if event.Bot {
+		err = s.repo.IncrementBotClick(ctx, req.Code)
+	} else {
+		count, err = s.repo.IncrementClick(ctx, req.Code, result.Variant, s.clickedEvent(event)...)
+	}
+	switch {
+	case errors.Is(err, storage.ErrClicksExhausted):
+		return s.fallback(link, errClicksExhausted)
+	case errors.Is(err, storage.ErrLinkNotFound):
+		return s.fallback(nil, errLinkNotFound)
+	case err != nil && link.MaxClicks > 0:
+		// 无法确认是否还有剩余次数时不跳转
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to record click"}
+	case err != nil:
+		log.Printf("failed to increment click count of %s: %v", req.Code, err)
+	case !event.Bot:
+		s.checkClickThreshold(ctx, req.Code, count)
+	}
+	// 时间桶同样在跳转前累加，与点击计数保持一致
+	if err == nil && s.clicks != nil {
+		if err := s.clicks.CountClick(ctx, req.Code, event.Bot, event.Timestamp); err != nil {
+			log.Printf("failed to count click stats of %s: %v", req.Code, err)
+		}
+	}
+	// 需要统计转化的链接为本次点击签发归因令牌；签发失败时照常跳转，只是无法归因
+	if link.TrackConversions && !event.Bot && !event.NoTrack && s.clicks != nil {
+		if err := s.attachAttribution(ctx, result, event); err != nil {
+			log.Printf("failed to create attribution for %s: %v", link.Code, err)
+		}
+	}
+	s.clickPipeline.Submit(event)
+
+	return result, nil
 }
 
-// GetLinkInfo 获取短链接详细信息
-func (s *LinkService) GetLinkInfo(ctx context.Context, code string) (*LinkInfoResponse, error) {
+// GetLinkInfo 获取短链接详细信息。
+// 受密码保护的链接只有在 password 正确时才返回目标地址，未提供密码时返回脱敏后的信息
+func (s *LinkService) GetLinkInfo(ctx context.Context, code, password string) (*LinkInfoResponse, error) {
 	link, err := s.repo.GetByCode(ctx, code)
 	if err != nil {
 		return nil, &ServiceError{Type: "internal_error", Message: "failed to query link"}
@@ -145,14 +701,119 @@ func (s *LinkService) GetLinkInfo(ctx context.Context, code string) (*LinkInfoRe
 		return nil, &ServiceError{Type: "not_found", Message: "short link not found"}
 	}
 
+	redacted := false
+	if link.PasswordHash != "" {
+		if password == "" {
+			redacted = true
+		} else if err := s.verifyPassword(ctx, link, password); err != nil {
+			return nil, err
+		}
+	}
+
+	info := newLinkInfo(link, redacted)
+	s.fillUniqueVisitors(ctx, info)
+	s.fillConversions(ctx, info)
+	return info, nil
+}
+
+// newLinkInfo 将记录转换为查询响应；redacted 为 true 时隐藏所有目标地址（受密码保护且未验证）
+func newLinkInfo(link *model.ShortLink, redacted bool) *LinkInfoResponse {
+	if redacted {
+		return &LinkInfoResponse{
+			Code:              link.Code,
+			CreatedAt:         link.CreatedAt,
+			ExpireAt:          link.ExpireAt,
+			ClickCount:        link.ClickCount,
+			BotClickCount:     link.BotClickCount,
+			LastAccessedAt:    link.LastAccessedAt,
+			ActivateAt:        link.ActivateAt,
+			Schedule:          link.Schedule,
+			MaxClicks:         link.MaxClicks,
+			PasswordProtected: true,
+			ForcePreview:      link.ForcePreview,
+			Disabled:          link.Disabled,
+			OGTitle:           link.OGTitle,
+			OGDescription:     link.OGDescription,
+			OGImage:           link.OGImage,
+			TrackConversions:  link.TrackConversions,
+			Health:            redactHealth(link.Health),
+		}
+	}
+
+	var destinations []DestinationInfo
+	for _, d := range link.Destinations {
+		destinations = append(destinations, DestinationInfo{
+			ID:         d.ID,
+			URL:        d.URL,
+			Weight:     d.Weight,
+			ClickCount: d.ClickCount,
+			Health:     d.Health,
+		})
+	}
+
 	return &LinkInfoResponse{
-		Code:           link.Code,
-		LongURL:        link.LongURL,
-		CreatedAt:      link.CreatedAt,
-		ExpireAt:       link.ExpireAt,
-		ClickCount:     link.ClickCount,
-		LastAccessedAt: link.LastAccessedAt,
-	}, nil
+		Code:              link.Code,
+		LongURL:           link.LongURL,
+		CreatedAt:         link.CreatedAt,
+		ExpireAt:          link.ExpireAt,
+		ClickCount:        link.ClickCount,
+		BotClickCount:     link.BotClickCount,
+		LastAccessedAt:    link.LastAccessedAt,
+		Destinations:      destinations,
+		RoutingMode:       link.RoutingMode,
+		Sticky:            link.StickyVariant,
+		LanguageURLs:      link.LanguageURLs,
+		ActivateAt:        link.ActivateAt,
+		Schedule:          link.Schedule,
+		FallbackURL:       link.FallbackURL,
+		MaxClicks:         link.MaxClicks,
+		PasswordProtected: link.PasswordHash != "",
+		ForcePreview:      link.ForcePreview,
+		Disabled:          link.Disabled,
+		OGTitle:           link.OGTitle,
+		OGDescription:     link.OGDescription,
+		OGImage:           link.OGImage,
+		AppURL:            link.AppURL,
+		IOSStoreURL:       link.IOSStoreURL,
+		AndroidStoreURL:   link.AndroidStoreURL,
+		HideReferrer:      link.HideReferrer,
+		TrackConversions:  link.TrackConversions,
+		Metadata:          link.Metadata,
+		Health:            link.Health,
+		LanguageHealth:    link.LanguageHealth,
+	}
+}
+
+// authorizeManage 校验修改链接（更新、删除、管理 webhook）的权限。
+// 服务没有账号体系，访问密码是唯一能证明所有权的凭证：未设置密码的链接创建后不能再通过 API 修改
+func (s *LinkService) authorizeManage(ctx context.Context, code, password string) (*model.ShortLink, error) {
+	link, err := s.repo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to query link"}
+	}
+	if link == nil {
+		return nil, errLinkNotFound
+	}
+	if link.PasswordHash == "" {
+		return nil, errManageForbidden
+	}
+	if password == "" {
+		return nil, errPasswordRequired
+	}
+	if err := s.verifyPassword(ctx, link, password); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// redactHealth 去掉健康检查的失败原因：错误信息中可能包含目标地址或其域名
+func redactHealth(h *model.HealthStatus) *model.HealthStatus {
+	if h == nil {
+		return nil
+	}
+	redacted := *h
+	redacted.Error = ""
+	return &redacted
 }
 
 // ServiceError 业务错误
@@ -165,6 +826,23 @@ func (e *ServiceError) Error() string {
 	return e.Message
 }
 
+// verifyPassword 校验访问密码，并按短码限制单位时间内的失败次数。
+// 先计数再校验，避免并发暴力破解绕过限制；校验通过后撤销本次计数
+func (s *LinkService) verifyPassword(ctx context.Context, link *model.ShortLink, password string) error {
+	attempts, err := s.repo.IncrementPasswordAttempts(ctx, link.Code, passwordAttemptWindow)
+	if err != nil {
+		return &ServiceError{Type: "internal_error", Message: "failed to verify password"}
+	}
+	if attempts > passwordAttemptLimit {
+		return errTooManyAttempts
+	}
+	if !util.CheckPassword(link.PasswordHash, password) {
+		return errInvalidPassword
+	}
+	_ = s.repo.DecrementPasswordAttempts(ctx, link.Code)
+	return nil
+}
+
 // generateUniqueRandomCode 生成唯一的随机短码（重试机制）
 func (s *LinkService) generateUniqueRandomCode(ctx context.Context) (string, error) {
 	maxRetries := 10
@@ -189,3 +867,354 @@ func (s *LinkService) generateUniqueRandomCode(ctx context.Context) (string, err
 
 	return "", errors.New("failed to generate unique code after retries")
 }
+
+// buildDestinations 校验 A/B 分流目标并补全默认 ID 与权重
+func buildDestinations(reqs []DestinationRequest) ([]model.Destination, error) {
+	if len(reqs) > maxDestinations {
+		return nil, util.ErrTooManyDestinations
+	}
+
+	destinations := make([]model.Destination, 0, len(reqs))
+	seen := make(map[string]bool, len(reqs))
+	for i, d := range reqs {
+		if err := util.ValidateURL(d.URL); err != nil {
+			return nil, err
+		}
+		id := d.ID
+		if id == "" {
+			id = "v" + strconv.Itoa(i+1)
+		}
+		if err := util.ValidateVariantID(id); err != nil {
+			return nil, err
+		}
+		if seen[id] {
+			return nil, util.ErrDuplicateVariantID
+		}
+		seen[id] = true
+
+		weight := d.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		if weight < 1 || weight > 1000 {
+			return nil, util.ErrInvalidWeight
+		}
+		destinations = append(destinations, model.Destination{ID: id, URL: d.URL, Weight: weight})
+	}
+	return destinations, nil
+}
+
+// selectDestination 按链接的 RoutingMode 选择目标；粘性分流时优先沿用访问者已分配的分组
+func (s *LinkService) selectDestination(ctx context.Context, link *model.ShortLink, variant string) model.Destination {
+	destinations := link.Destinations
+	if link.StickyVariant && variant != "" {
+		for _, d := range destinations {
+			if d.ID == variant {
+				return d
+			}
+		}
+	}
+
+	switch link.RoutingMode {
+	case model.RoutingRoundRobin:
+		n, err := s.repo.NextRoundRobin(ctx, link.Code)
+		if err != nil {
+			// 计数器不可用时退化为随机选择，保证仍可跳转
+			return destinations[rand.IntN(len(destinations))]
+		}
+		return destinations[(n-1)%int64(len(destinations))]
+	case model.RoutingFailover:
+		// 按顺序选择第一个可用目标（未检查过的视为可用），全部不可用时使用第一个
+		for _, d := range destinations {
+			if d.Health == nil || d.Health.Up {
+				return d
+			}
+		}
+		return destinations[0]
+	}
+	return pickWeighted(destinations)
+}
+
+// pickWeighted 按权重随机选择一个分组
+func pickWeighted(destinations []model.Destination) model.Destination {
+	total := 0
+	for _, d := range destinations {
+		total += d.Weight
+	}
+	n := rand.IntN(total)
+	for _, d := range destinations {
+		if n < d.Weight {
+			return d
+		}
+		n -= d.Weight
+	}
+	return destinations[len(destinations)-1]
+}
+
+// validateLanguageURLs 校验语言路由表的语言标签与目标地址
+func validateLanguageURLs(languageURLs map[string]string) error {
+	if len(languageURLs) > maxLanguageURLs {
+		return util.ErrTooManyLanguages
+	}
+	for tag, u := range languageURLs {
+		if err := util.ValidateLanguageTag(tag); err != nil {
+			return err
+		}
+		if err := util.ValidateURL(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchLanguageURL 根据 Accept-Language 协商出语言路由表中的标签
+func matchLanguageURL(languageURLs map[string]string, acceptLanguage string) string {
+	if acceptLanguage == "" {
+		return ""
+	}
+	tags := make([]string, 0, len(languageURLs))
+	for tag := range languageURLs {
+		tags = append(tags, tag)
+	}
+	// 保证同等条件下匹配结果稳定
+	sort.Strings(tags)
+	return util.MatchLanguage(acceptLanguage, tags)
+}
+
+// SetLinkDisabled 停用或重新启用短链接，需提供链接密码
+func (s *LinkService) SetLinkDisabled(ctx context.Context, code, password string, disabled bool) error {
+	link, err := s.authorizeManage(ctx, code, password)
+	if err != nil {
+		return err
+	}
+	link.Disabled = disabled
+
+	if err := s.repo.SetDisabled(ctx, code, disabled, s.linkEvent(model.EventLinkUpdated, link)...); err != nil {
+		if errors.Is(err, storage.ErrLinkNotFound) {
+			return errLinkNotFound
+		}
+		return &ServiceError{Type: "internal_error", Message: "failed to update link"}
+	}
+	s.notifyWebhooks(ctx, link, model.WebhookEventUpdated)
+	return nil
+}
+
+// UpdateLink 修改短链接的目标地址、过期时间、备用地址、未生效提示与点击上限，需提供链接密码
+func (s *LinkService) UpdateLink(ctx context.Context, code, password string, req *UpdateRequest) (*LinkInfoResponse, error) {
+	link, err := s.authorizeManage(ctx, code, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		if err := util.ValidateURL(*req.URL); err != nil {
+			return nil, &ServiceError{Type: "invalid_request", Message: err.Error()}
+		}
+		link.LongURL = *req.URL
+	}
+	if req.ClearExpireAt {
+		link.ExpireAt = nil
+	} else if req.ExpireAt != nil {
+		if req.ExpireAt.Before(time.Now()) {
+			return nil, &ServiceError{Type: "invalid_request", Message: "expire_at must be in the future"}
+		}
+		if link.ActivateAt != nil && !link.ActivateAt.Before(*req.ExpireAt) {
+			return nil, &ServiceError{Type: "invalid_request", Message: util.ErrActivateAfterExpire.Error()}
+		}
+		link.ExpireAt = req.ExpireAt
+	}
+	if req.FallbackURL != nil {
+		if *req.FallbackURL != "" {
+			if err := util.ValidateURL(*req.FallbackURL); err != nil {
+				return nil, &ServiceError{Type: "invalid_request", Message: "fallback_url: " + err.Error()}
+			}
+		}
+		link.FallbackURL = *req.FallbackURL
+	}
+	if req.InactiveMessage != nil {
+		if len(*req.InactiveMessage) > 256 {
+			return nil, &ServiceError{Type: "invalid_request", Message: "inactive_message exceeds maximum length of 256 characters"}
+		}
+		link.InactiveMessage = *req.InactiveMessage
+	}
+	if req.MaxClicks != nil {
+		if *req.MaxClicks < 0 {
+			return nil, &ServiceError{Type: "invalid_request", Message: "max_clicks must not be negative"}
+		}
+		link.MaxClicks = *req.MaxClicks
+	}
+
+	if err := s.repo.Update(ctx, link, s.linkEvent(model.EventLinkUpdated, link)...); err != nil {
+		if errors.Is(err, storage.ErrLinkNotFound) {
+			return nil, errLinkNotFound
+		}
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to update link"}
+	}
+	// 目标地址变化后重新抓取元数据
+	if req.URL != nil && s.metadataFetcher != nil {
+		s.startMetadataFetch(link.Code, link.LongURL)
+	}
+	s.notifyWebhooks(ctx, link, model.WebhookEventUpdated)
+
+	return newLinkInfo(link, false), nil
+}
+
+// DeleteLink 删除短链接及其点击数据与 webhook，需提供链接密码；删除前先推送 link.deleted
+func (s *LinkService) DeleteLink(ctx context.Context, code, password string) error {
+	link, err := s.authorizeManage(ctx, code, password)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, code, s.linkEvent(model.EventLinkDeleted, link)...); err != nil {
+		if errors.Is(err, storage.ErrLinkNotFound) {
+			return errLinkNotFound
+		}
+		return &ServiceError{Type: "internal_error", Message: "failed to delete link"}
+	}
+	if s.clicks != nil {
+		if err := s.clicks.DeleteClicks(ctx, code); err != nil {
+			log.Printf("failed to delete click data of %s: %v", code, err)
+		}
+	}
+	s.notifyWebhooks(ctx, link, model.WebhookEventDeleted)
+	if s.webhooks != nil {
+		if err := s.webhooks.DeleteWebhooks(ctx, code); err != nil {
+			log.Printf("failed to delete webhooks of %s: %v", code, err)
+		}
+		s.thresholds.invalidate(code)
+	}
+	return nil
+}
+
+// ListBrokenLinks 返回健康检查发现不可用的链接；受密码保护的链接只返回脱敏信息。
+// 已删除或已禁用的短码会顺带移出失效集合
+func (s *LinkService) ListBrokenLinks(ctx context.Context) ([]*LinkInfoResponse, error) {
+	codes, err := s.repo.ListBroken(ctx)
+	if err != nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to list broken links"}
+	}
+	sort.Strings(codes)
+
+	links := make([]*LinkInfoResponse, 0, len(codes))
+	for _, code := range codes {
+		link, err := s.repo.GetByCode(ctx, code)
+		if err != nil {
+			return nil, &ServiceError{Type: "internal_error", Message: "failed to get link"}
+		}
+		if link == nil || link.Disabled {
+			_ = s.repo.SetBroken(ctx, code, false)
+			continue
+		}
+		links = append(links, newLinkInfo(link, link.PasswordHash != ""))
+	}
+	return links, nil
+}
+
+// startMetadataFetch 在并发上限内启动一次异步抓取；已达上限时跳过本次抓取，
+// 避免大量创建请求堆积出无上限的等待中 goroutine
+func (s *LinkService) startMetadataFetch(code, longURL string) {
+	select {
+	case s.metadataSem <- struct{}{}:
+	default:
+		log.Printf("skipped metadata fetch for %s: too many fetches in flight", code)
+		return
+	}
+	s.metadataWG.Add(1)
+	go func() {
+		defer s.metadataWG.Done()
+		defer func() { <-s.metadataSem }()
+		s.fetchMetadata(code, longURL)
+	}()
+}
+
+// fetchMetadata 抓取目标页面元数据并保存；失败时记录失败原因
+func (s *LinkService) fetchMetadata(code, longURL string) {
+	ctx, cancel := context.WithTimeout(context.Background(), metadataFetchTimeout)
+	defer cancel()
+
+	metadata, err := s.metadataFetcher.Fetch(ctx, longURL)
+	if metadata == nil {
+		metadata = &model.PageMetadata{FetchedAt: time.Now().UTC()}
+	}
+	if err != nil {
+		metadata.Error = err.Error()
+	}
+	if err := s.repo.UpdateMetadata(ctx, code, metadata); err != nil && !errors.Is(err, storage.ErrLinkNotFound) {
+		log.Printf("failed to save metadata for %s: %v", code, err)
+	}
+}
+
+// fallback 链接不存在或不可用时，优先跳转链接自身的备用地址，其次是全局备用地址；都未配置时返回原错误。
+// 跳转备用地址不计入点击
+func (s *LinkService) fallback(link *model.ShortLink, svcErr *ServiceError) (*RedirectResult, error) {
+	if link != nil && link.FallbackURL != "" {
+		return &RedirectResult{LongURL: link.FallbackURL, HideReferrer: link.HideReferrer}, nil
+	}
+	if s.fallbackURL != "" {
+		return &RedirectResult{LongURL: s.fallbackURL}, nil
+	}
+	return nil, svcErr
+}
+
+// validateDeepLink 校验 App 深度链接与应用商店地址
+func validateDeepLink(req *CreateRequest) error {
+	if req.AppURL != "" {
+		if err := util.ValidateAppURL(req.AppURL); err != nil {
+			return errors.New("app_url: " + err.Error())
+		}
+	}
+	if req.IOSStoreURL != "" {
+		if err := util.ValidateURL(req.IOSStoreURL); err != nil {
+			return errors.New("ios_store_url: " + err.Error())
+		}
+	}
+	if req.AndroidStoreURL != "" {
+		if err := util.ValidateURL(req.AndroidStoreURL); err != nil {
+			return errors.New("android_store_url: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// validateSocialCard 校验社交卡片字段长度与图片地址
+func validateSocialCard(req *CreateRequest) error {
+	if len(req.OGTitle) > 200 {
+		return errors.New("og_title exceeds maximum length of 200 characters")
+	}
+	if len(req.OGDescription) > 500 {
+		return errors.New("og_description exceeds maximum length of 500 characters")
+	}
+	if req.OGImage != "" {
+		if err := util.ValidateURL(req.OGImage); err != nil {
+			return errors.New("og_image: " + err.Error())
+		}
+	}
+	return nil
+}
+
+// socialCard 根据链接的 OpenGraph 信息生成卡片（不含目标地址）
+func (s *LinkService) socialCard(link *model.ShortLink) *SocialCard {
+	return &SocialCard{
+		Title:       link.OGTitle,
+		Description: link.OGDescription,
+		Image:       link.OGImage,
+		ShortURL:    s.baseURL + "/" + link.Code,
+	}
+}
+
+// hasSocialCard 判断链接是否配置了卡片信息
+func hasSocialCard(link *model.ShortLink) bool {
+	return link.OGTitle != "" || link.OGDescription != "" || link.OGImage != ""
+}
+
+// linkActive 判断短链接在 now 时刻是否生效，未生效时返回默认提示信息
+func linkActive(link *model.ShortLink, now time.Time) (string, bool) {
+	if link.ActivateAt != nil && now.Before(*link.ActivateAt) {
+		return "short link is not active yet", false
+	}
+	if !scheduleActive(link.Schedule, now) {
+		return "short link is outside its active window", false
+	}
+	return "", true
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSetLinkDisabledRequiresPassword(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepo()
+	s := NewLinkService(repo, "http://localhost")
+	defer func() { _ = s.Close(ctx) }()
+
+	for _, req := range []*CreateRequest{
+		{CustomCode: "public", URL: "https://example.com"},
+		{CustomCode: "locked", URL: "https://example.com", Password: "s3cret-pass"},
+	} {
+		if _, err := s.CreateShortLink(ctx, req); err != nil {
+			t.Fatalf("CreateShortLink(%s): %v", req.CustomCode, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		password string
+		wantType string
+	}{
+		{"password-less link", "public", "", "forbidden"},
+		{"password-less link with password", "public", "anything", "forbidden"},
+		{"missing password", "locked", "", "password_required"},
+		{"wrong password", "locked", "wrong-pass", "invalid_password"},
+		{"missing link", "missing", "s3cret-pass", "not_found"},
+		{"correct password", "locked", "s3cret-pass", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := s.SetLinkDisabled(ctx, tt.code, tt.password, true)
+			if tt.wantType == "" {
+				if err != nil {
+					t.Fatalf("SetLinkDisabled: %v", err)
+				}
+				if link, _ := repo.GetByCode(ctx, tt.code); !link.Disabled {
+					t.Error("link not disabled")
+				}
+				return
+			}
+			var serr *ServiceError
+			if !errors.As(err, &serr) || serr.Type != tt.wantType {
+				t.Fatalf("SetLinkDisabled error = %v, want type %q", err, tt.wantType)
+			}
+			if link, _ := repo.GetByCode(ctx, tt.code); link != nil && link.Disabled {
+				t.Error("link disabled without authorization")
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"url-shortener/backend/internal/util"
+)
+
+// 访客 IP 的处理方式
+const (
+	// IPModeTruncate 截断为网段（默认 IPv4 /24、IPv6 /48）
+	IPModeTruncate = "truncate"
+	// IPModeHash 使用（可轮换的）密钥做 HMAC 哈希，只能判断是否为同一 IP
+	IPModeHash = "hash"
+	// IPModeDrop 不保存 IP
+	IPModeDrop = "drop"
+)
+
+// clickRetentionInterval 清理过期点击事件的间隔
+const clickRetentionInterval = time.Hour
+
+// PrivacyPolicy 访客数据处理策略，作用于重定向产生的所有统计数据
+type PrivacyPolicy struct {
+	// IPMode 为事件中保存 IP 的方式：truncate / hash / drop
+	IPMode string
+	// IPv4PrefixBits / IPv6PrefixBits 为 truncate 模式保留的前缀长度
+	IPv4PrefixBits int
+	IPv6PrefixBits int
+	// RotateSalt 为 true 时访客指纹与 IP 哈希使用每天（UTC）轮换的随机密钥，
+	// 旧密钥过期后无法再关联不同日期的访问；独立访客总数因此按天累加
+	RotateSalt bool
+	// HonorDoNotTrack 为 true 时带 DNT: 1 或 Sec-GPC: 1 的访问只计入点击次数与时间桶，
+	// 不记录原始事件、来源排行、独立访客与实时推送
+	HonorDoNotTrack bool
+	// ClickRetention 原始点击事件的保留时长（0 表示不按时间清理），聚合统计不受影响
+	ClickRetention time.Duration
+}
+
+// DefaultPrivacyPolicy 默认策略：截断 IP、固定密钥、尊重 DNT、不按时间清理原始事件
+func DefaultPrivacyPolicy() PrivacyPolicy {
+	return PrivacyPolicy{
+		IPMode:          IPModeTruncate,
+		IPv4PrefixBits:  24,
+		IPv6PrefixBits:  48,
+		HonorDoNotTrack: true,
+	}
+}
+
+// Validate 检查策略配置是否合法
+func (p PrivacyPolicy) Validate() error {
+	switch p.IPMode {
+	case IPModeTruncate, IPModeHash, IPModeDrop:
+	default:
+		return &ServiceError{Type: "invalid_request", Message: "ip mode must be truncate, hash or drop"}
+	}
+	if p.IPv4PrefixBits < 0 || p.IPv4PrefixBits > 32 || p.IPv6PrefixBits < 0 || p.IPv6PrefixBits > 128 {
+		return &ServiceError{Type: "invalid_request", Message: "ip prefix bits out of range"}
+	}
+	if p.ClickRetention < 0 {
+		return &ServiceError{Type: "invalid_request", Message: "click retention must not be negative"}
+	}
+	return nil
+}
+
+// WithPrivacyPolicy 设置访客数据处理策略
+func WithPrivacyPolicy(policy PrivacyPolicy) Option {
+	return func(s *LinkService) {
+		s.privacy = policy
+	}
+}
+
+// saltCache 缓存当天的轮换密钥，避免每次重定向都访问存储
+type saltCache struct {
+	mu   sync.Mutex
+	day  string
+	salt []byte
+}
+
+// visitorSaltFor 返回计算访客指纹与 IP 哈希所用的密钥：开启轮换时为当天的共享随机密钥，否则为固定密钥
+func (s *LinkService) visitorSaltFor(ctx context.Context, t time.Time) ([]byte, error) {
+	if !s.privacy.RotateSalt || s.clicks == nil {
+		return s.visitorSalt, nil
+	}
+
+	day := t.UTC().Format("20060102")
+	s.salts.mu.Lock()
+	defer s.salts.mu.Unlock()
+	if s.salts.day == day {
+		return s.salts.salt, nil
+	}
+	salt, err := s.clicks.DailySalt(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+	s.salts.day = day
+	s.salts.salt = salt
+	return salt, nil
+}
+
+// anonymizeIP 按策略处理访客 IP
+func (s *LinkService) anonymizeIP(ip string, salt []byte) string {
+	switch s.privacy.IPMode {
+	case IPModeHash:
+		return util.HashIP(salt, ip)
+	case IPModeTruncate:
+		return util.TruncateIP(ip, s.privacy.IPv4PrefixBits, s.privacy.IPv6PrefixBits)
+	}
+	return ""
+}
+
+// RunClickRetention 定期删除超过保留时长的原始点击事件与 outbox 中的领域事件（其中包含点击的来源与地区），
+// 直到 ctx 取消；未配置保留时长时立即返回
+func (s *LinkService) RunClickRetention(ctx context.Context) {
+	if s.privacy.ClickRetention <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(clickRetentionInterval)
+	defer ticker.Stop()
+	for {
+		before := time.Now().Add(-s.privacy.ClickRetention)
+		if s.clicks != nil {
+			deleted, err := s.clicks.TrimClicks(ctx, before)
+			if err != nil && ctx.Err() == nil {
+				log.Printf("failed to purge expired click events: %v", err)
+			} else if deleted > 0 {
+				log.Printf("purged %d expired click events", deleted)
+			}
+		}
+		// 超过保留期仍未投递的事件同样删除（sink 长时间不可用时会丢失这些事件）
+		deleted, err := s.repo.PurgeOutbox(ctx, before)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("failed to purge expired outbox events: %v", err)
+		} else if deleted > 0 {
+			log.Printf("purged %d expired outbox events", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
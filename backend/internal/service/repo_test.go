@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/storage"
+)
+
+// memoryRepo 在内存中保存链接的测试仓储，只实现测试用到的方法
+type memoryRepo struct {
+	storage.LinkRepository
+	links   map[string]model.ShortLink
+	counter map[string]int64
+}
+
+func newMemoryRepo() *memoryRepo {
+	return &memoryRepo{links: make(map[string]model.ShortLink), counter: make(map[string]int64)}
+}
+
+func (r *memoryRepo) Create(_ context.Context, link *model.ShortLink, _ ...*model.DomainEvent) (*model.ShortLink, error) {
+	r.links[link.Code] = *link
+	return link, nil
+}
+
+func (r *memoryRepo) GetByCode(_ context.Context, code string) (*model.ShortLink, error) {
+	link, ok := r.links[code]
+	if !ok {
+		return nil, nil
+	}
+	return &link, nil
+}
+
+func (r *memoryRepo) IncrementClick(_ context.Context, code, _ string, _ ...*model.DomainEvent) (int64, error) {
+	link, ok := r.links[code]
+	if !ok {
+		return 0, storage.ErrLinkNotFound
+	}
+	if link.MaxClicks > 0 && link.ClickCount+link.BotClickCount >= link.MaxClicks {
+		return 0, storage.ErrClicksExhausted
+	}
+	link.ClickCount++
+	r.links[code] = link
+	return link.ClickCount, nil
+}
+
+func (r *memoryRepo) IncrementBotClick(_ context.Context, code string) error {
+	link, ok := r.links[code]
+	if !ok {
+		return storage.ErrLinkNotFound
+	}
+	if link.MaxClicks > 0 && link.ClickCount+link.BotClickCount >= link.MaxClicks {
+		return storage.ErrClicksExhausted
+	}
+	link.BotClickCount++
+	r.links[code] = link
+	return nil
+}
+
+func (r *memoryRepo) NextRoundRobin(_ context.Context, code string) (int64, error) {
+	r.counter[code]++
+	return r.counter[code], nil
+}
+
+func (r *memoryRepo) SetDisabled(_ context.Context, code string, disabled bool, _ ...*model.DomainEvent) error {
+	link, ok := r.links[code]
+	if !ok {
+		return storage.ErrLinkNotFound
+	}
+	link.Disabled = disabled
+	r.links[code] = link
+	return nil
+}
+
+func (r *memoryRepo) IncrementPasswordAttempts(_ context.Context, _ string, _ time.Duration) (int64, error) {
+	return 1, nil
+}
+
+func (r *memoryRepo) DecrementPasswordAttempts(_ context.Context, _ string) error {
+	return nil
+}
+
+func (r *memoryRepo) Delete(_ context.Context, code string, _ ...*model.DomainEvent) error {
+	if _, ok := r.links[code]; !ok {
+		return storage.ErrLinkNotFound
+	}
+	delete(r.links, code)
+	return nil
+}
+
+// memoryWebhooks 在内存中保存 webhook 的测试仓储，记录 ListWebhooks 调用次数与入队的推送
+type memoryWebhooks struct {
+	storage.WebhookRepository
+	hooks      map[string][]*model.Webhook
+	lists      int
+	deliveries []*model.WebhookDelivery
+	// failCreate 为 true 时 CreateWebhook 返回错误
+	failCreate bool
+}
+
+func newMemoryWebhooks() *memoryWebhooks {
+	return &memoryWebhooks{hooks: make(map[string][]*model.Webhook)}
+}
+
+func (w *memoryWebhooks) CreateWebhook(_ context.Context, hook *model.Webhook) error {
+	if w.failCreate {
+		return errors.New("webhook store unavailable")
+	}
+	w.hooks[hook.Code] = append(w.hooks[hook.Code], hook)
+	return nil
+}
+
+func (w *memoryWebhooks) ListWebhooks(_ context.Context, code string) ([]*model.Webhook, error) {
+	w.lists++
+	return w.hooks[code], nil
+}
+
+func (w *memoryWebhooks) DeleteWebhooks(_ context.Context, code string) error {
+	delete(w.hooks, code)
+	return nil
+}
+
+func (w *memoryWebhooks) EnqueueDelivery(_ context.Context, delivery *model.WebhookDelivery) error {
+	w.deliveries = append(w.deliveries, delivery)
+	return nil
+}
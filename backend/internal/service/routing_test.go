@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/storage"
+)
+
+// roundRobinRepo 只实现 NextRoundRobin 的测试仓储
+type roundRobinRepo struct {
+	storage.LinkRepository
+	counter int64
+	err     error
+}
+
+func (r *roundRobinRepo) NextRoundRobin(_ context.Context, _ string) (int64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	r.counter++
+	return r.counter, nil
+}
+
+func testDestinations() []model.Destination {
+	return []model.Destination{
+		{ID: "a", URL: "https://a.example.com", Weight: 1},
+		{ID: "b", URL: "https://b.example.com", Weight: 1},
+		{ID: "c", URL: "https://c.example.com", Weight: 1},
+	}
+}
+
+func TestSelectDestinationRoundRobin(t *testing.T) {
+	s := &LinkService{repo: &roundRobinRepo{}}
+	link := &model.ShortLink{Code: "rr", RoutingMode: model.RoutingRoundRobin, Destinations: testDestinations()}
+
+	want := []string{"a", "b", "c", "a", "b"}
+	for i, id := range want {
+		if got := s.selectDestination(context.Background(), link, ""); got.ID != id {
+			t.Fatalf("pick %d = %q, want %q", i, got.ID, id)
+		}
+	}
+}
+
+func TestSelectDestinationRoundRobinFallback(t *testing.T) {
+	s := &LinkService{repo: &roundRobinRepo{err: errors.New("redis down")}}
+	link := &model.ShortLink{Code: "rr", RoutingMode: model.RoutingRoundRobin, Destinations: testDestinations()}
+
+	// 计数器不可用时仍应返回某个目标
+	for i := 0; i < 20; i++ {
+		if got := s.selectDestination(context.Background(), link, ""); got.ID == "" {
+			t.Fatal("fallback returned empty destination")
+		}
+	}
+}
+
+func TestSelectDestinationFailover(t *testing.T) {
+	up := &model.HealthStatus{Up: true}
+	down := &model.HealthStatus{Up: false}
+
+	tests := []struct {
+		name   string
+		health []*model.HealthStatus
+		want   string
+	}{
+		{"first up", []*model.HealthStatus{up, up, up}, "a"},
+		{"unchecked counts as up", []*model.HealthStatus{nil, down, up}, "a"},
+		{"skips down", []*model.HealthStatus{down, up, up}, "b"},
+		{"skips several down", []*model.HealthStatus{down, down, nil}, "c"},
+		{"all down uses first", []*model.HealthStatus{down, down, down}, "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destinations := testDestinations()
+			for i := range destinations {
+				destinations[i].Health = tt.health[i]
+			}
+			s := &LinkService{}
+			link := &model.ShortLink{Code: "fo", RoutingMode: model.RoutingFailover, Destinations: destinations}
+			if got := s.selectDestination(context.Background(), link, ""); got.ID != tt.want {
+				t.Errorf("selectDestination() = %q, want %q", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectDestinationWeighted(t *testing.T) {
+	tests := []struct {
+		name    string
+		weights []int
+		// allowed 可能被选中的目标
+		allowed map[string]bool
+	}{
+		{"single non-zero weight", []int{0, 5, 0}, map[string]bool{"b": true}},
+		{"zero weight never picked", []int{3, 0, 1}, map[string]bool{"a": true, "c": true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			destinations := testDestinations()
+			for i := range destinations {
+				destinations[i].Weight = tt.weights[i]
+			}
+			s := &LinkService{}
+			link := &model.ShortLink{Code: "w", RoutingMode: model.RoutingWeighted, Destinations: destinations}
+			for i := 0; i < 200; i++ {
+				if got := s.selectDestination(context.Background(), link, ""); !tt.allowed[got.ID] {
+					t.Fatalf("selectDestination() = %q, not in %v", got.ID, tt.allowed)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectDestinationWeightedDistribution(t *testing.T) {
+	destinations := testDestinations()
+	destinations[0].Weight = 80
+	destinations[1].Weight = 20
+	destinations[2].Weight = 0
+
+	counts := make(map[string]int)
+	const n = 10000
+	for i := 0; i < n; i++ {
+		counts[pickWeighted(destinations).ID]++
+	}
+	// 80/20 权重，允许较宽的误差避免偶发失败
+	if share := float64(counts["a"]) / n; share < 0.75 || share > 0.85 {
+		t.Errorf("share of a = %.3f, want about 0.80 (counts %v)", share, counts)
+	}
+	if counts["c"] != 0 {
+		t.Errorf("zero-weight destination picked %d times", counts["c"])
+	}
+}
+
+func TestSelectDestinationStickyVariant(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		variant string
+		want    string
+	}{
+		{"sticky overrides round robin", model.RoutingRoundRobin, "c", "c"},
+		{"sticky overrides failover", model.RoutingFailover, "b", "b"},
+		{"unknown variant falls through", model.RoutingRoundRobin, "zzz", "a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &LinkService{repo: &roundRobinRepo{}}
+			link := &model.ShortLink{Code: "s", RoutingMode: tt.mode, StickyVariant: true, Destinations: testDestinations()}
+			if got := s.selectDestination(context.Background(), link, tt.variant); got.ID != tt.want {
+				t.Errorf("selectDestination() = %q, want %q", got.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestRoutingModeRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepo()
+	s := NewLinkService(repo, "http://localhost")
+	defer func() { _ = s.Close(ctx) }()
+
+	_, err := s.CreateShortLink(ctx, &CreateRequest{
+		CustomCode:  "mirrors",
+		RoutingMode: model.RoutingRoundRobin,
+		Destinations: []DestinationRequest{
+			{URL: "https://a.example.com"},
+			{URL: "https://b.example.com"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CreateShortLink: %v", err)
+	}
+
+	link, err := repo.GetByCode(ctx, "mirrors")
+	if err != nil || link == nil {
+		t.Fatalf("GetByCode = %v, %v", link, err)
+	}
+	if link.RoutingMode != model.RoutingRoundRobin {
+		t.Fatalf("stored RoutingMode = %q, want %q", link.RoutingMode, model.RoutingRoundRobin)
+	}
+
+	want := []string{"https://a.example.com", "https://b.example.com", "https://a.example.com"}
+	for i, url := range want {
+		result, err := s.GetLongURL(ctx, &RedirectRequest{Code: "mirrors", UserAgent: "Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0"})
+		if err != nil {
+			t.Fatalf("GetLongURL: %v", err)
+		}
+		if result.LongURL != url {
+			t.Fatalf("redirect %d = %q, want %q", i, result.LongURL, url)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/util"
+)
+
+const maxScheduleWindows = 20
+
+// weekdayNames 星期缩写与 time.Weekday 的映射
+var weekdayNames = map[string][]time.Weekday{
+	"mon":      {time.Monday},
+	"tue":      {time.Tuesday},
+	"wed":      {time.Wednesday},
+	"thu":      {time.Thursday},
+	"fri":      {time.Friday},
+	"sat":      {time.Saturday},
+	"sun":      {time.Sunday},
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekends": {time.Saturday, time.Sunday},
+}
+
+// validateSchedule 校验周期性生效窗口（时区、星期、HH:MM 时间）
+func validateSchedule(schedule *model.Schedule) error {
+	if schedule == nil {
+		return nil
+	}
+	if _, err := loadScheduleLocation(schedule.Timezone); err != nil {
+		return util.ErrInvalidTimezone
+	}
+	if len(schedule.Windows) == 0 {
+		return util.ErrEmptySchedule
+	}
+	if len(schedule.Windows) > maxScheduleWindows {
+		return util.ErrTooManyWindows
+	}
+	for _, w := range schedule.Windows {
+		start, okStart := parseClock(w.Start)
+		end, okEnd := parseClock(w.End)
+		if !okStart || !okEnd || start == end {
+			return util.ErrInvalidWindowTime
+		}
+		for _, day := range w.Days {
+			if _, ok := weekdayNames[strings.ToLower(day)]; !ok {
+				return util.ErrInvalidWindowDay
+			}
+		}
+	}
+	return nil
+}
+
+// scheduleActive 判断时间点 t 是否落在任一窗口内。
+// 窗口 end 早于 start 表示跨零点（如 22:00-06:00），此时按窗口开始那天匹配星期
+func scheduleActive(schedule *model.Schedule, t time.Time) bool {
+	if schedule == nil {
+		return true
+	}
+	loc, err := loadScheduleLocation(schedule.Timezone)
+	if err != nil {
+		return false
+	}
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+
+	for _, w := range schedule.Windows {
+		start, _ := parseClock(w.Start)
+		end, _ := parseClock(w.End)
+		if start < end {
+			if minute >= start && minute < end && matchWeekday(w.Days, local.Weekday()) {
+				return true
+			}
+			continue
+		}
+		// 跨零点窗口：当天 start 之后，或前一天窗口延续到今天 end 之前
+		if minute >= start && matchWeekday(w.Days, local.Weekday()) {
+			return true
+		}
+		if minute < end && matchWeekday(w.Days, local.AddDate(0, 0, -1).Weekday()) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWeekday 判断星期是否在窗口的 days 列表中，列表为空表示每天
+func matchWeekday(days []string, weekday time.Weekday) bool {
+	if len(days) == 0 {
+		return true
+	}
+	for _, day := range days {
+		for _, wd := range weekdayNames[strings.ToLower(day)] {
+			if wd == weekday {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseClock 解析 HH:MM，返回当天的分钟数（24:00 表示一天结束）
+func parseClock(s string) (int, bool) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		if s == "24:00" {
+			return 24 * 60, true
+		}
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// scheduleLocations 缓存已加载的时区（名称 -> *time.Location），
+// 避免每次重定向都重新读取时区数据库；只缓存有效的 IANA 时区名，数量有限
+var scheduleLocations sync.Map
+
+func loadScheduleLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if loc, ok := scheduleLocations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	scheduleLocations.Store(name, loc)
+	return loc, nil
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/util"
+)
+
+func TestValidateSchedule(t *testing.T) {
+	tooMany := make([]model.ScheduleWindow, maxScheduleWindows+1)
+	for i := range tooMany {
+		tooMany[i] = model.ScheduleWindow{Start: "09:00", End: "17:00"}
+	}
+
+	tests := []struct {
+		name     string
+		schedule *model.Schedule
+		want     error
+	}{
+		{"nil", nil, nil},
+		{"valid", &model.Schedule{Timezone: "Asia/Shanghai", Windows: []model.ScheduleWindow{{Days: []string{"weekdays", "Sat"}, Start: "09:00", End: "24:00"}}}, nil},
+		{"overnight", &model.Schedule{Windows: []model.ScheduleWindow{{Start: "22:00", End: "06:00"}}}, nil},
+		{"bad timezone", &model.Schedule{Timezone: "Mars/Olympus", Windows: []model.ScheduleWindow{{Start: "09:00", End: "17:00"}}}, util.ErrInvalidTimezone},
+		{"no windows", &model.Schedule{}, util.ErrEmptySchedule},
+		{"too many windows", &model.Schedule{Windows: tooMany}, util.ErrTooManyWindows},
+		{"bad time", &model.Schedule{Windows: []model.ScheduleWindow{{Start: "9am", End: "17:00"}}}, util.ErrInvalidWindowTime},
+		{"out of range time", &model.Schedule{Windows: []model.ScheduleWindow{{Start: "09:00", End: "25:00"}}}, util.ErrInvalidWindowTime},
+		{"empty window", &model.Schedule{Windows: []model.ScheduleWindow{{Start: "09:00", End: "09:00"}}}, util.ErrInvalidWindowTime},
+		{"bad day", &model.Schedule{Windows: []model.ScheduleWindow{{Days: []string{"funday"}, Start: "09:00", End: "17:00"}}}, util.ErrInvalidWindowDay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateSchedule(tt.schedule); !errors.Is(err, tt.want) {
+				t.Errorf("validateSchedule() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestScheduleActive(t *testing.T) {
+	businessHours := &model.Schedule{
+		Timezone: "Asia/Shanghai",
+		Windows:  []model.ScheduleWindow{{Days: []string{"weekdays"}, Start: "09:00", End: "18:00"}},
+	}
+	// 周五晚上开始、跨零点到周六早上
+	fridayNight := &model.Schedule{
+		Timezone: "America/New_York",
+		Windows:  []model.ScheduleWindow{{Days: []string{"fri"}, Start: "22:00", End: "06:00"}},
+	}
+	untilMidnight := &model.Schedule{
+		Windows: []model.ScheduleWindow{{Start: "20:00", End: "24:00"}},
+	}
+
+	// 2024-01-05 为周五，2024-01-06 为周六
+	tests := []struct {
+		name     string
+		schedule *model.Schedule
+		at       string
+		want     bool
+	}{
+		{"nil schedule", nil, "2024-01-06T12:00:00Z", true},
+		{"weekday inside local window", businessHours, "2024-01-05T03:00:00Z", true},
+		{"start inclusive", businessHours, "2024-01-05T01:00:00Z", true},
+		{"end exclusive", businessHours, "2024-01-05T10:00:00Z", false},
+		{"before local window", businessHours, "2024-01-05T00:59:00Z", false},
+		{"utc weekday but local saturday", businessHours, "2024-01-05T17:00:00Z", false},
+		{"local monday before window", businessHours, "2024-01-07T23:30:00Z", false},
+		{"utc sunday evening is local monday morning", businessHours, "2024-01-08T02:00:00Z", true},
+		{"overnight before midnight", fridayNight, "2024-01-06T03:30:00Z", true},
+		{"overnight after midnight", fridayNight, "2024-01-06T10:00:00Z", true},
+		{"overnight ends", fridayNight, "2024-01-06T11:00:00Z", false},
+		{"overnight on wrong day", fridayNight, "2024-01-05T03:30:00Z", false},
+		{"thursday night does not spill into friday", fridayNight, "2024-01-05T10:00:00Z", false},
+		{"24:00 end", untilMidnight, "2024-01-06T23:59:00Z", true},
+		{"24:00 end excludes midnight", untilMidnight, "2024-01-07T00:00:00Z", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, err := time.Parse(time.RFC3339, tt.at)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := scheduleActive(tt.schedule, at); got != tt.want {
+				t.Errorf("scheduleActive(%s) = %v, want %v", at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadScheduleLocationCached(t *testing.T) {
+	first, err := loadScheduleLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("loadScheduleLocation() error = %v", err)
+	}
+	second, err := loadScheduleLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("loadScheduleLocation() error = %v", err)
+	}
+	if first != second {
+		t.Error("loadScheduleLocation() did not reuse the cached location")
+	}
+	if _, err := loadScheduleLocation("Mars/Olympus"); err == nil {
+		t.Error("loadScheduleLocation() accepted an unknown timezone")
+	}
+	if _, ok := scheduleLocations.Load("Mars/Olympus"); ok {
+		t.Error("loadScheduleLocation() cached an unknown timezone")
+	}
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"url-shortener/backend/internal/model"
+)
+
+const (
+	// maxHourlyBuckets / maxDailyBuckets 单次查询最多返回的桶数（与存储保留时长一致）
+	maxHourlyBuckets = 31 * 24
+	maxDailyBuckets  = 400
+)
+
+// StatsRequest 点击统计查询参数，from/to 支持 RFC3339 或 YYYY-MM-DD（UTC）
+type StatsRequest struct {
+	From     string `form:"from"`
+	To       string `form:"to"`
+	Interval string `form:"interval"`
+}
+
+// StatsResponse 按时间桶划分的点击统计
+type StatsResponse struct {
+	Code     string    `json:"code"`
+	Interval string    `json:"interval"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Total    int64     `json:"total"`
+	// BotTotal 为区间内机器人访问次数（不计入 Total）
+	BotTotal int64 `json:"bot_total"`
+	// Uniques 为整个区间合并后的独立访客数（仅按天统计时提供）
+	Uniques *int64 `json:"uniques,omitempty"`
+	// Conversions 为区间内的转化次数，ConversionRate 为转化次数 / 人工点击数
+	Conversions    int64               `json:"conversions"`
+	ConversionRate float64             `json:"conversion_rate"`
+	Buckets        []model.StatsBucket `json:"buckets"`
+}
+
+// GetClickStats 返回短链接在时间范围内按小时或按天统计的点击数。
+// 默认区间：按小时为最近 24 小时，按天为最近 30 天
+func (s *LinkService) GetClickStats(ctx context.Context, code string, req *StatsRequest) (*StatsResponse, error) {
+	if s.clicks == nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "click statistics are not enabled"}
+	}
+
+	interval := req.Interval
+	if interval == "" {
+		interval = model.StatsIntervalDay
+	}
+	if interval != model.StatsIntervalHour && interval != model.StatsIntervalDay {
+		return nil, &ServiceError{Type: "invalid_request", Message: "interval must be hour or day"}
+	}
+
+	to := time.Now().UTC()
+	if req.To != "" {
+		t, dateOnly, err := parseStatsTime(req.To)
+		if err != nil {
+			return nil, &ServiceError{Type: "invalid_request", Message: "to must be RFC3339 or YYYY-MM-DD"}
+		}
+		// 只给日期时包含当天全部时间
+		if dateOnly {
+			t = t.Add(24*time.Hour - time.Nanosecond)
+		}
+		to = t
+	}
+	var from time.Time
+	if req.From != "" {
+		t, _, err := parseStatsTime(req.From)
+		if err != nil {
+			return nil, &ServiceError{Type: "invalid_request", Message: "from must be RFC3339 or YYYY-MM-DD"}
+		}
+		from = t
+	} else if interval == model.StatsIntervalHour {
+		from = to.Add(-23 * time.Hour)
+	} else {
+		from = to.AddDate(0, 0, -29)
+	}
+
+	from = truncateToBucket(interval, from)
+	if from.After(to) {
+		return nil, &ServiceError{Type: "invalid_request", Message: "from must be before to"}
+	}
+	if interval == model.StatsIntervalHour && to.Sub(from) >= maxHourlyBuckets*time.Hour {
+		return nil, &ServiceError{Type: "invalid_request", Message: "hourly stats range must not exceed 31 days"}
+	}
+	if interval == model.StatsIntervalDay && to.Sub(from) >= maxDailyBuckets*24*time.Hour {
+		return nil, &ServiceError{Type: "invalid_request", Message: "daily stats range must not exceed 400 days"}
+	}
+
+	link, err := s.repo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to query link"}
+	}
+	if link == nil {
+		return nil, errLinkNotFound
+	}
+
+	buckets, err := s.clicks.ClickStats(ctx, code, interval, from, to)
+	if err != nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to query click stats"}
+	}
+
+	resp := &StatsResponse{
+		Code:     code,
+		Interval: interval,
+		From:     from,
+		To:       to,
+		Buckets:  buckets,
+	}
+	for _, b := range buckets {
+		resp.Total += b.Clicks
+		resp.BotTotal += b.BotClicks
+		resp.Conversions += b.Conversions
+	}
+	if resp.Total > 0 {
+		resp.ConversionRate = float64(resp.Conversions) / float64(resp.Total)
+	}
+	if interval == model.StatsIntervalDay {
+		uniques, err := s.clicks.UniqueVisitorsBetween(ctx, code, from, to)
+		if err != nil {
+			return nil, &ServiceError{Type: "internal_error", Message: "failed to query unique visitors"}
+		}
+		resp.Uniques = &uniques
+	}
+	return resp, nil
+}
+
+// parseStatsTime 解析 RFC3339 时间或 YYYY-MM-DD 日期（按 UTC），dateOnly 表示输入只有日期
+func parseStatsTime(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), false, nil
+	}
+	t, err = time.Parse("2006-01-02", value)
+	return t, true, err
+}
+
+// truncateToBucket 将时间对齐到所在桶的起点（UTC）
+func truncateToBucket(interval string, t time.Time) time.Time {
+	t = t.UTC()
+	if interval == model.StatsIntervalHour {
+		return t.Truncate(time.Hour)
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+const (
+	defaultBreakdownLimit = 10
+	maxBreakdownLimit     = 100
+)
+
+// BreakdownResponse 某个维度的点击来源排行
+type BreakdownResponse struct {
+	Code      string                `json:"code"`
+	Dimension string                `json:"dimension"`
+	Total     int64                 `json:"total"`
+	Items     []model.BreakdownItem `json:"items"`
+}
+
+// GetBreakdown 返回短链接按来源域名、设备、浏览器或国家划分的点击排行（前 limit 个）
+func (s *LinkService) GetBreakdown(ctx context.Context, code, dimension string, limit int) (*BreakdownResponse, error) {
+	if s.clicks == nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "click statistics are not enabled"}
+	}
+
+	switch dimension {
+	case model.DimensionReferrer, model.DimensionDevice, model.DimensionBrowser, model.DimensionCountry:
+	default:
+		return nil, &ServiceError{Type: "invalid_request", Message: "dimension must be referrer, device, browser or country"}
+	}
+	if limit == 0 {
+		limit = defaultBreakdownLimit
+	}
+	if limit < 0 || limit > maxBreakdownLimit {
+		return nil, &ServiceError{Type: "invalid_request", Message: "limit must be between 1 and 100"}
+	}
+
+	link, err := s.repo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to query link"}
+	}
+	if link == nil {
+		return nil, errLinkNotFound
+	}
+
+	items, total, err := s.clicks.Breakdown(ctx, code, dimension, limit)
+	if err != nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to query breakdown"}
+	}
+	return &BreakdownResponse{
+		Code:      code,
+		Dimension: dimension,
+		Total:     total,
+		Items:     items,
+	}, nil
+}
+
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
+)
+
+// LeaderboardEntry 排行榜中的一项
+type LeaderboardEntry struct {
+	Rank int `json:"rank"`
+	// Clicks 为窗口内的人工点击数
+	Clicks int64             `json:"clicks"`
+	Link   *LinkInfoResponse `json:"link"`
+}
+
+// LeaderboardResponse 滚动窗口内点击最多的短链接
+type LeaderboardResponse struct {
+	Window string             `json:"window"`
+	Links  []LeaderboardEntry `json:"links"`
+}
+
+// GetLeaderboard 返回最近 1h / 24h / 7d 内点击最多的短链接；
+// 受密码保护的链接只返回脱敏信息，已删除的链接会被跳过
+func (s *LinkService) GetLeaderboard(ctx context.Context, window string, limit int) (*LeaderboardResponse, error) {
+	if s.clicks == nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "click statistics are not enabled"}
+	}
+
+	if window == "" {
+		window = model.LeaderboardDay
+	}
+	switch window {
+	case model.LeaderboardHour, model.LeaderboardDay, model.LeaderboardWeek:
+	default:
+		return nil, &ServiceError{Type: "invalid_request", Message: "window must be 1h, 24h or 7d"}
+	}
+	if limit == 0 {
+		limit = defaultLeaderboardLimit
+	}
+	if limit < 0 || limit > maxLeaderboardLimit {
+		return nil, &ServiceError{Type: "invalid_request", Message: "limit must be between 1 and 100"}
+	}
+
+	ranked, err := s.clicks.TopLinks(ctx, window, limit)
+	if err != nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to query leaderboard"}
+	}
+
+	resp := &LeaderboardResponse{Window: window, Links: make([]LeaderboardEntry, 0, len(ranked))}
+	for _, r := range ranked {
+		link, err := s.repo.GetByCode(ctx, r.Code)
+		if err != nil {
+			return nil, &ServiceError{Type: "internal_error", Message: "failed to query link"}
+		}
+		if link == nil {
+			continue
+		}
+		resp.Links = append(resp.Links, LeaderboardEntry{
+			Rank:   len(resp.Links) + 1,
+			Clicks: r.Clicks,
+			Link:   newLinkInfo(link, link.PasswordHash != ""),
+		})
+	}
+	return resp, nil
+}
@@ -0,0 +1,448 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/storage"
+	"url-shortener/backend/internal/util"
+)
+
+const (
+	// maxWebhooksPerLink 每个短链接最多注册的 webhook 数
+	maxWebhooksPerLink = 10
+	// defaultDeliveryLogLimit / maxDeliveryLogLimit 投递日志默认与最多返回的条数
+	defaultDeliveryLogLimit = 20
+	maxDeliveryLogLimit     = 100
+	// expiryCheckInterval / expiryBatchSize 检查过期链接的间隔与每次取出的数量
+	expiryCheckInterval = time.Minute
+	expiryBatchSize     = 100
+	// expiryClaimLease 领取到期短码后的租约，超时未确认的短码会被重新领取
+	expiryClaimLease = 5 * time.Minute
+	// thresholdCacheTTL 点击阈值缓存的有效期，其他实例上注册或删除的 webhook 最迟在该时间后生效；
+	// thresholdCacheSize 缓存的短码数超过该值时清理过期条目
+	thresholdCacheTTL  = 30 * time.Second
+	thresholdCacheSize = 10000
+)
+
+// WebhookRequest 注册 webhook 的参数
+type WebhookRequest struct {
+	URL string `json:"url"`
+	// Events 订阅的事件，省略时订阅所有生命周期事件（设置了 click_threshold 时包括 link.click_threshold）
+	Events []string `json:"events,omitempty"`
+	// ClickThreshold 点击次数达到该值时推送一次 link.click_threshold
+	ClickThreshold int64 `json:"click_threshold,omitempty"`
+	// Secret 签名密钥，省略时随机生成
+	Secret string `json:"secret,omitempty"`
+}
+
+// WebhookResponse webhook 信息，Secret 只在创建时返回
+type WebhookResponse struct {
+	*model.Webhook
+	Secret string `json:"secret,omitempty"`
+}
+
+// WebhookPayload 推送给 webhook 的事件内容；受密码保护的链接只包含脱敏信息
+type WebhookPayload struct {
+	// ID 与请求头 X-Webhook-Delivery 相同，重试时不变，接收方可据此去重
+	ID         string            `json:"id"`
+	Event      string            `json:"event"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	Link       *LinkInfoResponse `json:"link"`
+	// ClickThreshold 仅 link.click_threshold 事件提供
+	ClickThreshold int64 `json:"click_threshold,omitempty"`
+}
+
+// CreateWebhook 在短链接上注册 webhook，需提供链接密码
+func (s *LinkService) CreateWebhook(ctx context.Context, code, password string, req *WebhookRequest) (*WebhookResponse, error) {
+	if s.webhooks == nil {
+		return nil, errWebhooksDisabled
+	}
+	hook, err := buildWebhook(code, req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.authorizeManage(ctx, code, password); err != nil {
+		return nil, err
+	}
+	existing, err := s.webhooks.ListWebhooks(ctx, code)
+	if err != nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to list webhooks"}
+	}
+	if len(existing) >= maxWebhooksPerLink {
+		return nil, &ServiceError{Type: "invalid_request", Message: "webhooks exceed maximum of 10 per link"}
+	}
+
+	if err := s.webhooks.CreateWebhook(ctx, hook); err != nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to create webhook"}
+	}
+	s.thresholds.invalidate(code)
+	return &WebhookResponse{Webhook: hook, Secret: hook.Secret}, nil
+}
+
+// ListWebhooks 列出短链接上注册的 webhook（不含密钥），需提供链接密码
+func (s *LinkService) ListWebhooks(ctx context.Context, code, password string) ([]*model.Webhook, error) {
+	if s.webhooks == nil {
+		return nil, errWebhooksDisabled
+	}
+	if _, err := s.authorizeManage(ctx, code, password); err != nil {
+		return nil, err
+	}
+	hooks, err := s.webhooks.ListWebhooks(ctx, code)
+	if err != nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to list webhooks"}
+	}
+	if hooks == nil {
+		hooks = []*model.Webhook{}
+	}
+	return hooks, nil
+}
+
+// DeleteWebhook 删除短链接上的 webhook，需提供链接密码；已入队的推送仍会继续投递
+func (s *LinkService) DeleteWebhook(ctx context.Context, code, password, id string) error {
+	if s.webhooks == nil {
+		return errWebhooksDisabled
+	}
+	if _, err := s.authorizeManage(ctx, code, password); err != nil {
+		return err
+	}
+	if err := s.webhooks.DeleteWebhook(ctx, code, id); err != nil {
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			return errWebhookNotFound
+		}
+		return &ServiceError{Type: "internal_error", Message: "failed to delete webhook"}
+	}
+	s.thresholds.invalidate(code)
+	return nil
+}
+
+// ListWebhookDeliveries 返回 webhook 最近的投递记录（新的在前），需提供链接密码
+func (s *LinkService) ListWebhookDeliveries(ctx context.Context, code, password, id string, limit int) ([]*model.WebhookDelivery, error) {
+	if s.webhooks == nil {
+		return nil, errWebhooksDisabled
+	}
+	if _, err := s.authorizeManage(ctx, code, password); err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = defaultDeliveryLogLimit
+	}
+	if limit > maxDeliveryLogLimit {
+		limit = maxDeliveryLogLimit
+	}
+
+	hooks, err := s.webhooks.ListWebhooks(ctx, code)
+	if err != nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to list webhooks"}
+	}
+	found := false
+	for _, hook := range hooks {
+		if hook.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, errWebhookNotFound
+	}
+
+	deliveries, err := s.webhooks.ListDeliveries(ctx, id, limit)
+	if err != nil {
+		return nil, &ServiceError{Type: "internal_error", Message: "failed to list webhook deliveries"}
+	}
+	if deliveries == nil {
+		deliveries = []*model.WebhookDelivery{}
+	}
+	return deliveries, nil
+}
+
+// buildWebhook 校验注册参数并生成 webhook（ID 与缺省的密钥随机生成）
+func buildWebhook(code string, req *WebhookRequest) (*model.Webhook, error) {
+	if err := util.ValidateURL(req.URL); err != nil {
+		return nil, &ServiceError{Type: "invalid_request", Message: "webhook url: " + err.Error()}
+	}
+	if req.ClickThreshold < 0 {
+		return nil, &ServiceError{Type: "invalid_request", Message: "click_threshold must not be negative"}
+	}
+	if len(req.Secret) > 256 {
+		return nil, &ServiceError{Type: "invalid_request", Message: "webhook secret exceeds maximum length of 256 characters"}
+	}
+
+	events := req.Events
+	if len(events) == 0 {
+		events = []string{model.WebhookEventCreated, model.WebhookEventUpdated, model.WebhookEventDeleted, model.WebhookEventExpired}
+		if req.ClickThreshold > 0 {
+			events = append(events, model.WebhookEventClickThreshold)
+		}
+	}
+	seen := make(map[string]bool, len(events))
+	for _, event := range events {
+		if !validWebhookEvent(event) {
+			return nil, &ServiceError{Type: "invalid_request", Message: "unknown webhook event: " + event}
+		}
+		if seen[event] {
+			return nil, &ServiceError{Type: "invalid_request", Message: "duplicate webhook event: " + event}
+		}
+		seen[event] = true
+	}
+	if seen[model.WebhookEventClickThreshold] && req.ClickThreshold == 0 {
+		return nil, &ServiceError{Type: "invalid_request", Message: "click_threshold is required for link.click_threshold"}
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		secret = randomHex(24)
+	}
+	return &model.Webhook{
+		ID:             randomHex(8),
+		Code:           code,
+		URL:            req.URL,
+		Secret:         secret,
+		Events:         events,
+		ClickThreshold: req.ClickThreshold,
+		CreatedAt:      time.Now().UTC(),
+	}, nil
+}
+
+func validWebhookEvent(event string) bool {
+	for _, e := range model.WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// notifyWebhooks 为订阅了 event 的 webhook 各入队一条推送；失败时记录日志并返回第一个错误，
+// 一般调用方忽略错误（不影响触发事件的操作），过期通知据此决定是否稍后重试。
+// 需要在删除 webhook 之前调用（link.deleted）
+func (s *LinkService) notifyWebhooks(ctx context.Context, link *model.ShortLink, event string) error {
+	if s.webhooks == nil {
+		return nil
+	}
+	hooks, err := s.webhooks.ListWebhooks(ctx, link.Code)
+	if err != nil {
+		log.Printf("failed to list webhooks of %s: %v", link.Code, err)
+		return err
+	}
+	var firstErr error
+	for _, hook := range hooks {
+		if !hook.Subscribes(event) {
+			continue
+		}
+		if err := s.enqueueDelivery(ctx, hook, link, event); err != nil {
+			log.Printf("failed to enqueue webhook delivery for %s: %v", link.Code, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// checkClickThreshold 点击次数累加到 count 后调用：恰好达到阈值的 webhook 推送一次 link.click_threshold。
+// 每次累加 1 且累加是原子的，因此每个阈值只会被一次点击命中；
+// 跳转路径上只查询缓存的阈值，计数命中阈值时才读取 webhook 列表
+func (s *LinkService) checkClickThreshold(ctx context.Context, code string, count int64) {
+	if s.webhooks == nil {
+		return
+	}
+	thresholds, ok := s.thresholds.get(code, time.Now())
+	if !ok {
+		hooks, err := s.webhooks.ListWebhooks(ctx, code)
+		if err != nil {
+			log.Printf("failed to list webhooks of %s: %v", code, err)
+			return
+		}
+		thresholds = clickThresholds(hooks)
+		s.thresholds.set(code, thresholds, time.Now())
+	}
+	if !slices.Contains(thresholds, count) {
+		return
+	}
+
+	hooks, err := s.webhooks.ListWebhooks(ctx, code)
+	if err != nil {
+		log.Printf("failed to list webhooks of %s: %v", code, err)
+		return
+	}
+
+	var link *model.ShortLink
+	for _, hook := range hooks {
+		if hook.ClickThreshold != count || !hook.Subscribes(model.WebhookEventClickThreshold) {
+			continue
+		}
+		if link == nil {
+			link, err = s.repo.GetByCode(ctx, code)
+			if err != nil || link == nil {
+				return
+			}
+		}
+		if err := s.enqueueDelivery(ctx, hook, link, model.WebhookEventClickThreshold); err != nil {
+			log.Printf("failed to enqueue webhook delivery for %s: %v", code, err)
+		}
+	}
+}
+
+// clickThresholds 返回订阅了 link.click_threshold 的 webhook 的阈值
+func clickThresholds(hooks []*model.Webhook) []int64 {
+	var thresholds []int64
+	for _, hook := range hooks {
+		if hook.ClickThreshold > 0 && hook.Subscribes(model.WebhookEventClickThreshold) {
+			thresholds = append(thresholds, hook.ClickThreshold)
+		}
+	}
+	return thresholds
+}
+
+// thresholdCache 缓存各短码的点击阈值（没有阈值的短码同样缓存），避免每次跳转都读取 webhook 列表
+type thresholdCache struct {
+	mu      sync.Mutex
+	entries map[string]thresholdEntry
+}
+
+type thresholdEntry struct {
+	thresholds []int64
+	expiresAt  time.Time
+}
+
+func (c *thresholdCache) get(code string, now time.Time) ([]int64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[code]
+	if !ok || now.After(entry.expiresAt) {
+		return nil, false
+	}
+	return entry.thresholds, true
+}
+
+func (c *thresholdCache) set(code string, thresholds []int64, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]thresholdEntry)
+	}
+	if len(c.entries) >= thresholdCacheSize {
+		for k, entry := range c.entries {
+			if now.After(entry.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+	}
+	if len(c.entries) >= thresholdCacheSize {
+		// 全部未过期时整体清空，缓存只是优化
+		c.entries = make(map[string]thresholdEntry)
+	}
+	c.entries[code] = thresholdEntry{thresholds: thresholds, expiresAt: now.Add(thresholdCacheTTL)}
+}
+
+// invalidate 在本实例注册或删除 webhook 后丢弃该短码的缓存
+func (c *thresholdCache) invalidate(code string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, code)
+}
+
+// enqueueDelivery 生成事件内容并写入投递队列，由 webhook.Dispatcher 异步推送
+func (s *LinkService) enqueueDelivery(ctx context.Context, hook *model.Webhook, link *model.ShortLink, event string) error {
+	now := time.Now().UTC()
+	payload := &WebhookPayload{
+		ID:         randomHex(16),
+		Event:      event,
+		OccurredAt: now,
+		Link:       newLinkInfo(link, link.PasswordHash != ""),
+	}
+	if event == model.WebhookEventClickThreshold {
+		payload.ClickThreshold = hook.ClickThreshold
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	delivery := &model.WebhookDelivery{
+		ID:        payload.ID,
+		WebhookID: hook.ID,
+		Code:      link.Code,
+		Event:     event,
+		URL:       hook.URL,
+		Secret:    hook.Secret,
+		Payload:   data,
+		Status:    model.DeliveryPending,
+		CreatedAt: now,
+	}
+	return s.webhooks.EnqueueDelivery(ctx, delivery)
+}
+
+// RunExpiryNotifier 定期检查到达 expire_at 的链接，推送 link.expired 并写入领域事件，直到 ctx 取消
+func (s *LinkService) RunExpiryNotifier(ctx context.Context) {
+	// 过期队列上线前创建的链接不在队列中，启动时补录一次
+	if err := s.repo.BackfillExpiring(ctx, time.Now()); err != nil && ctx.Err() == nil {
+		log.Printf("failed to backfill expiring links: %v", err)
+	}
+
+	ticker := time.NewTicker(expiryCheckInterval)
+	defer ticker.Stop()
+	for {
+		s.notifyExpired(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// notifyExpired 领取所有已到期的短码并逐个通知。推送入队成功后才确认（移出过期队列并写入 link.expired 事件），
+// 进程在两者之间崩溃时，租约到期后短码会被重新领取（至少推送一次）
+func (s *LinkService) notifyExpired(ctx context.Context) {
+	for {
+		now := time.Now()
+		until := now.Add(expiryClaimLease)
+		codes, err := s.repo.ClaimExpired(ctx, now, until, expiryBatchSize)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("failed to check expired links: %v", err)
+			}
+			return
+		}
+		for _, code := range codes {
+			link, err := s.repo.GetByCode(ctx, code)
+			if err != nil {
+				log.Printf("failed to query expired link %s: %v", code, err)
+				continue
+			}
+			// 领取后被删除或延长了有效期的链接不通知，确认时也不会移出重新入队的短码
+			var events []*model.DomainEvent
+			if link != nil && link.ExpireAt != nil && !link.ExpireAt.After(now) {
+				if err := s.notifyWebhooks(ctx, link, model.WebhookEventExpired); err != nil {
+					continue
+				}
+				events = s.linkEvent(model.EventLinkExpired, link)
+			}
+			if err := s.repo.AckExpired(ctx, code, until, events...); err != nil {
+				log.Printf("failed to ack expired link %s: %v", code, err)
+			}
+		}
+		if len(codes) < expiryBatchSize {
+			return
+		}
+	}
+}
+
+// randomHex 生成 n 个随机字节的十六进制字符串
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
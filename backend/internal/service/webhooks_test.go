@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"url-shortener/backend/internal/model"
+)
+
+func TestThresholdCache(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var c thresholdCache
+
+	if _, ok := c.get("abc123", now); ok {
+		t.Fatal("empty cache returned an entry")
+	}
+	c.set("abc123", []int64{10, 100}, now)
+	c.set("nohook", nil, now)
+
+	tests := []struct {
+		name   string
+		code   string
+		at     time.Time
+		want   []int64
+		wantOK bool
+	}{
+		{"cached thresholds", "abc123", now.Add(time.Second), []int64{10, 100}, true},
+		{"cached empty result", "nohook", now.Add(time.Second), nil, true},
+		{"unknown code", "other", now, nil, false},
+		{"expired", "abc123", now.Add(thresholdCacheTTL + time.Second), nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := c.get(tt.code, tt.at)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("get(%s) = %v, %v, want %v, %v", tt.code, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+
+	c.invalidate("abc123")
+	if _, ok := c.get("abc123", now); ok {
+		t.Error("invalidated entry still cached")
+	}
+}
+
+func TestCheckClickThresholdUsesCache(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepo()
+	hooks := newMemoryWebhooks()
+	s := NewLinkService(repo, "http://localhost", WithWebhooks(hooks))
+	defer func() { _ = s.Close(ctx) }()
+
+	_, err := s.CreateShortLink(ctx, &CreateRequest{
+		CustomCode: "campaign",
+		URL:        "https://example.com",
+		Password:   "s3cret-pass",
+		Webhooks:   []WebhookRequest{{URL: "https://hooks.example.com", Events: []string{model.WebhookEventClickThreshold}, ClickThreshold: 3}},
+	})
+	if err != nil {
+		t.Fatalf("CreateShortLink: %v", err)
+	}
+
+	hooks.lists = 0
+	for count := int64(1); count <= 5; count++ {
+		s.checkClickThreshold(ctx, "campaign", count)
+	}
+	// 首次填充缓存一次，命中阈值时再读取一次
+	if hooks.lists != 2 {
+		t.Errorf("ListWebhooks called %d times, want 2", hooks.lists)
+	}
+	if len(hooks.deliveries) != 1 || hooks.deliveries[0].Event != model.WebhookEventClickThreshold {
+		t.Fatalf("deliveries = %+v, want one link.click_threshold", hooks.deliveries)
+	}
+}
+
+func TestCreateShortLinkWebhooks(t *testing.T) {
+	hook := WebhookRequest{URL: "https://hooks.example.com"}
+	tests := []struct {
+		name       string
+		password   string
+		failCreate bool
+		wantType   string
+	}{
+		{"password-less link", "", false, "invalid_request"},
+		{"webhook store failure rolls back", "s3cret-pass", true, "internal_error"},
+		{"registered", "s3cret-pass", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := newMemoryRepo()
+			hooks := newMemoryWebhooks()
+			hooks.failCreate = tt.failCreate
+			s := NewLinkService(repo, "http://localhost", WithWebhooks(hooks))
+			defer func() { _ = s.Close(ctx) }()
+
+			resp, err := s.CreateShortLink(ctx, &CreateRequest{
+				CustomCode: "hooked",
+				URL:        "https://example.com",
+				Password:   tt.password,
+				Webhooks:   []WebhookRequest{hook, hook},
+			})
+			link, _ := repo.GetByCode(ctx, "hooked")
+			if tt.wantType == "" {
+				if err != nil {
+					t.Fatalf("CreateShortLink: %v", err)
+				}
+				if link == nil || len(resp.Webhooks) != 2 || len(hooks.hooks["hooked"]) != 2 {
+					t.Fatalf("link = %v, webhooks = %d, want link with 2 webhooks", link, len(hooks.hooks["hooked"]))
+				}
+				return
+			}
+			var serr *ServiceError
+			if !errors.As(err, &serr) || serr.Type != tt.wantType {
+				t.Fatalf("CreateShortLink error = %v, want type %q", err, tt.wantType)
+			}
+			if link != nil || len(hooks.hooks["hooked"]) != 0 {
+				t.Errorf("link = %v, webhooks = %d, want nothing left behind", link, len(hooks.hooks["hooked"]))
+			}
+		})
+	}
+}
@@ -0,0 +1,513 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	redisv9 "github.com/redis/go-redis/v9"
+
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/storage"
+)
+
+const (
+	// clickStreamMaxLen 每个短码的点击事件流最多保留的条数（近似裁剪）
+	clickStreamMaxLen = 100000
+	// hourlyStatsRetention / dailyStatsRetention 小时桶与天桶的保留时长
+	hourlyStatsRetention = 31 * 24 * time.Hour
+	dailyStatsRetention  = 400 * 24 * time.Hour
+	// breakdownMaxMembers 每个维度最多保留的取值数，超出时淘汰点击数最少的取值
+	breakdownMaxMembers = 1000
+	// clickPageSize 遍历点击事件流时每页读取的条数
+	clickPageSize = 500
+	// leaderboardCacheTTL 窗口合并结果的缓存时长
+	leaderboardCacheTTL = 10 * time.Second
+	// liveClicksChannel 实时点击事件的 pub/sub 频道
+	liveClicksChannel = "shortener:clicks:live"
+	// breakdownNone 维度取值为空时使用的占位值（无来源、未知国家）
+	breakdownNone = "(none)"
+)
+
+// leaderboardSlot 排行榜分片粒度：每个分片是一个 sorted set，保留时长略长于其参与的最大窗口
+type leaderboardSlot struct {
+	name      string
+	width     time.Duration
+	retention time.Duration
+}
+
+// leaderboardWindow 滚动窗口由最近 count 个分片合并得到
+type leaderboardWindow struct {
+	slot  leaderboardSlot
+	count int
+}
+
+var (
+	slot5m = leaderboardSlot{name: "5m", width: 5 * time.Minute, retention: 2 * time.Hour}
+	slot1h = leaderboardSlot{name: "1h", width: time.Hour, retention: 8 * 24 * time.Hour}
+
+	leaderboardWindows = map[string]leaderboardWindow{
+		model.LeaderboardHour: {slot: slot5m, count: 12},
+		model.LeaderboardDay:  {slot: slot1h, count: 24},
+		model.LeaderboardWeek: {slot: slot1h, count: 7 * 24},
+	}
+)
+
+// RedisClickRepository 使用 Redis Stream 保存原始点击事件
+//
+// Key 设计：
+//   - 点击事件流：shortener:clicks:{code} (stream)
+//     fields: ts (Unix 毫秒), variant, ref, ua, browser, country, ip, bot (1 表示机器人)
+//   - 小时统计桶：shortener:stats:{code}:hour:{YYYYMMDDHH} (hash，保留 31 天)
+//   - 天统计桶：shortener:stats:{code}:day:{YYYYMMDD} (hash，保留 400 天)
+//     fields: clicks, bot_clicks, conversions
+//   - 排行榜分片：shortener:top:5m:{slot} (sorted set，保留 2 小时)、shortener:top:1h:{slot} (sorted set，保留 8 天)
+//     member 为短码，score 为分片内的人工点击数；shortener:top:window:{1h|24h|7d} 为合并结果的短期缓存
+//   - 每日密钥：shortener:salt:{YYYYMMDD} (string，开启密钥轮换时用于访客指纹与 IP 哈希，保留 48 小时)
+//   - 实时点击：shortener:clicks:live (pub/sub 频道，消息为 JSON 格式的点击事件，不含 IP)
+//   - 来源排行：shortener:breakdown:{code}:{referrer|device|browser|country} (sorted set，score 为点击数)
+//   - 独立访客：shortener:uv:{code} (HyperLogLog，总计)、shortener:uv:{code}:day:{YYYYMMDD} (HyperLogLog，保留 400 天)
+//   - 归因令牌：shortener:attr:{token} (hash，TTL 为归因窗口)
+//     fields: code, variant, ts (点击时间 Unix 毫秒), goal:{goal} (已转化的目标，用于去重)
+//   - 转化汇总：shortener:conv:{code} (hash)
+//     fields: total, goal:{goal} (各目标转化数), value:{goal} (各目标累计金额)
+type RedisClickRepository struct {
+	rdb *redisv9.Client
+}
+
+func NewClickRepository(rdb *redisv9.Client) storage.ClickRepository {
+	return &RedisClickRepository{rdb: rdb}
+}
+
+func (r *RedisClickRepository) CountClick(ctx context.Context, code string, bot bool, at time.Time) error {
+	// 机器人访问单独计数，不进入人工点击的统计与排行
+	field := "clicks"
+	if bot {
+		field = "bot_clicks"
+	}
+	hourKey := statsKey(code, model.StatsIntervalHour, at)
+	dayKey := statsKey(code, model.StatsIntervalDay, at)
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redisv9.Pipeliner) error {
+		pipe.HIncrBy(ctx, hourKey, field, 1)
+		pipe.Expire(ctx, hourKey, hourlyStatsRetention)
+		pipe.HIncrBy(ctx, dayKey, field, 1)
+		pipe.Expire(ctx, dayKey, dailyStatsRetention)
+		if bot {
+			return nil
+		}
+		for _, slot := range []leaderboardSlot{slot5m, slot1h} {
+			key := leaderboardKey(slot, at)
+			pipe.ZIncrBy(ctx, key, 1, code)
+			pipe.Expire(ctx, key, slot.retention)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *RedisClickRepository) RecordClick(ctx context.Context, event *model.ClickEvent) error {
+	// 访客要求不跟踪时不写原始事件与明细统计，计数已由 CountClick 完成
+	if event.NoTrack {
+		return nil
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	var add *redisv9.StringCmd
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redisv9.Pipeliner) error {
+		add = pipe.XAdd(ctx, &redisv9.XAddArgs{
+			Stream: "shortener:clicks:" + event.Code,
+			MaxLen: clickStreamMaxLen,
+			Approx: true,
+			Values: []interface{}{
+				"ts", strconv.FormatInt(event.Timestamp.UnixMilli(), 10),
+				"variant", event.Variant,
+				"ref", event.ReferrerHost,
+				"ua", event.UserAgentClass,
+				"browser", event.Browser,
+				"country", event.Country,
+				"ip", event.IP,
+				"bot", formatBool(event.Bot),
+			},
+		})
+
+		// 机器人访问不进入人工点击的来源排行与独立访客
+		if event.Bot {
+			return nil
+		}
+
+		for dimension, value := range map[string]string{
+			model.DimensionReferrer: event.ReferrerHost,
+			model.DimensionDevice:   event.UserAgentClass,
+			model.DimensionBrowser:  event.Browser,
+			model.DimensionCountry:  event.Country,
+		} {
+			if value == "" {
+				value = breakdownNone
+			}
+			key := breakdownKey(event.Code, dimension)
+			pipe.ZIncrBy(ctx, key, 1, value)
+			pipe.ZRemRangeByRank(ctx, key, 0, -breakdownMaxMembers-1)
+		}
+		if event.VisitorID != "" {
+			dailyUV := uniqueVisitorsKey(event.Code, event.Timestamp)
+			pipe.PFAdd(ctx, "shortener:uv:"+event.Code, event.VisitorID)
+			pipe.PFAdd(ctx, dailyUV, event.VisitorID)
+			pipe.Expire(ctx, dailyUV, dailyStatsRetention)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	event.ID = add.Val()
+
+	// 广播给所有实例的实时订阅者；广播失败不影响事件记录
+	live := *event
+	live.IP = ""
+	if data, err := json.Marshal(&live); err == nil {
+		_ = r.rdb.Publish(ctx, liveClicksChannel, data).Err()
+	}
+	return nil
+}
+
+func (r *RedisClickRepository) DeleteClicks(ctx context.Context, code string) error {
+	keys := []string{
+		"shortener:clicks:" + code,
+		"shortener:uv:" + code,
+		"shortener:conv:" + code,
+	}
+	for _, dimension := range []string{model.DimensionReferrer, model.DimensionDevice, model.DimensionBrowser, model.DimensionCountry} {
+		keys = append(keys, breakdownKey(code, dimension))
+	}
+	now := time.Now()
+	keys = append(keys, retainedDailyKeys(code, now)...)
+	for window := range leaderboardWindows {
+		keys = append(keys, "shortener:top:window:"+window)
+	}
+
+	// 按保留期内可能存在的 key 逐个删除，避免 SCAN 整个 keyspace；
+	// 分批执行，单条 DEL 命令的参数不会过多
+	pipe := r.rdb.Pipeline()
+	for len(keys) > 0 {
+		n := min(len(keys), 500)
+		pipe.Del(ctx, keys[:n]...)
+		keys = keys[n:]
+	}
+	// 排行榜分片由所有短码共享，只移除该短码
+	for _, key := range retainedLeaderboardKeys(now) {
+		pipe.ZRem(ctx, key, code)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// retainedDailyKeys 返回短码在保留期内可能存在的小时桶、天桶与每日独立访客 key（更早的已随 TTL 过期）
+func retainedDailyKeys(code string, now time.Time) []string {
+	var keys []string
+	for t := now.Add(-hourlyStatsRetention); !t.After(now.Add(time.Hour)); t = t.Add(time.Hour) {
+		keys = append(keys, statsKey(code, model.StatsIntervalHour, t))
+	}
+	for t := now.Add(-dailyStatsRetention); !t.After(now.AddDate(0, 0, 1)); t = t.AddDate(0, 0, 1) {
+		keys = append(keys, statsKey(code, model.StatsIntervalDay, t), uniqueVisitorsKey(code, t))
+	}
+	return keys
+}
+
+// retainedLeaderboardKeys 返回保留期内可能存在的排行榜分片 key
+func retainedLeaderboardKeys(now time.Time) []string {
+	var keys []string
+	for _, slot := range []leaderboardSlot{slot5m, slot1h} {
+		for t := now.Add(-slot.retention); !t.After(now.Add(slot.width)); t = t.Add(slot.width) {
+			keys = append(keys, leaderboardKey(slot, t))
+		}
+	}
+	return keys
+}
+
+func (r *RedisClickRepository) TrimClicks(ctx context.Context, before time.Time) (int64, error) {
+	minID := strconv.FormatInt(before.UnixMilli(), 10)
+	var deleted int64
+	iter := r.rdb.ScanType(ctx, 0, "shortener:clicks:*", 200, "stream").Iterator()
+	for iter.Next(ctx) {
+		// 精确裁剪（非近似），保证超过保留期的事件都被删除
+		n, err := r.rdb.XTrimMinID(ctx, iter.Val(), minID).Result()
+		if err != nil {
+			return deleted, err
+		}
+		deleted += n
+	}
+	return deleted, iter.Err()
+}
+
+// dailySaltTTL 每日密钥的保留时长：覆盖当天及跨零点时仍在处理的事件，之后删除且不可恢复
+const dailySaltTTL = 48 * time.Hour
+
+func (r *RedisClickRepository) DailySalt(ctx context.Context, day time.Time) ([]byte, error) {
+	key := "shortener:salt:" + day.UTC().Format("20060102")
+
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	// 多个实例同时生成时只有第一个写入生效，其余实例读取已写入的值
+	if err := r.rdb.SetNX(ctx, key, salt, dailySaltTTL).Err(); err != nil {
+		return nil, err
+	}
+	return r.rdb.Get(ctx, key).Bytes()
+}
+
+func (r *RedisClickRepository) TopLinks(ctx context.Context, window string, limit int) ([]model.RankedLink, error) {
+	w, ok := leaderboardWindows[window]
+	if !ok {
+		return nil, nil
+	}
+
+	// 合并窗口内的分片并缓存一小段时间，避免每次查询都对上百个分片做 ZUNIONSTORE
+	cacheKey := "shortener:top:window:" + window
+	exists, err := r.rdb.Exists(ctx, cacheKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	if exists == 0 {
+		now := time.Now()
+		keys := make([]string, w.count)
+		for i := range keys {
+			keys[i] = leaderboardKey(w.slot, now.Add(-time.Duration(i)*w.slot.width))
+		}
+		_, err := r.rdb.TxPipelined(ctx, func(pipe redisv9.Pipeliner) error {
+			pipe.ZUnionStore(ctx, cacheKey, &redisv9.ZStore{Keys: keys})
+			pipe.Expire(ctx, cacheKey, leaderboardCacheTTL)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	top, err := r.rdb.ZRevRangeWithScores(ctx, cacheKey, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	links := make([]model.RankedLink, 0, len(top))
+	for _, z := range top {
+		links = append(links, model.RankedLink{Code: z.Member.(string), Clicks: int64(z.Score)})
+	}
+	return links, nil
+}
+
+// leaderboardKey 返回时间 t 所在排行榜分片的 key
+func leaderboardKey(slot leaderboardSlot, t time.Time) string {
+	return "shortener:top:" + slot.name + ":" + strconv.FormatInt(t.Unix()/int64(slot.width/time.Second), 10)
+}
+
+func (r *RedisClickRepository) SubscribeClicks(ctx context.Context) (<-chan *model.ClickEvent, error) {
+	pubsub := r.rdb.Subscribe(ctx, liveClicksChannel)
+	// 等待订阅确认，连接失败时立即返回错误
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		return nil, err
+	}
+
+	events := make(chan *model.ClickEvent, 256)
+	go func() {
+		defer close(events)
+		defer func() { _ = pubsub.Close() }()
+
+		// Channel 在连接断开后会自动重连并重新订阅
+		messages := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-messages:
+				if !ok {
+					return
+				}
+				event := &model.ClickEvent{}
+				if err := json.Unmarshal([]byte(msg.Payload), event); err != nil {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (r *RedisClickRepository) ForEachClick(ctx context.Context, code string, from, to time.Time, fn func(event *model.ClickEvent) error) error {
+	stream := "shortener:clicks:" + code
+	// Stream entry ID 以毫秒时间戳开头，可直接按时间范围查询
+	// from 为零值（或早于 1970 年）时从最早的事件开始，负数不是合法的 entry ID
+	start := "-"
+	if ms := from.UnixMilli(); ms > 0 {
+		start = strconv.FormatInt(ms, 10)
+	}
+	end := strconv.FormatInt(to.UnixMilli(), 10)
+	for {
+		messages, err := r.rdb.XRangeN(ctx, stream, start, end, clickPageSize).Result()
+		if err != nil {
+			return err
+		}
+		for _, msg := range messages {
+			if err := fn(parseClickEntry(code, msg)); err != nil {
+				return err
+			}
+		}
+		if len(messages) < clickPageSize {
+			return nil
+		}
+		// 下一页从上一页最后一条之后开始（排他区间）
+		start = "(" + messages[len(messages)-1].ID
+	}
+}
+
+// parseClickEntry 将事件流中的一条记录还原为点击事件
+func parseClickEntry(code string, msg redisv9.XMessage) *model.ClickEvent {
+	field := func(name string) string {
+		v, _ := msg.Values[name].(string)
+		return v
+	}
+	event := &model.ClickEvent{
+		ID:             msg.ID,
+		Code:           code,
+		Variant:        field("variant"),
+		ReferrerHost:   field("ref"),
+		UserAgentClass: field("ua"),
+		Browser:        field("browser"),
+		Country:        field("country"),
+		IP:             field("ip"),
+		Bot:            field("bot") == "1",
+	}
+	if ms, err := strconv.ParseInt(field("ts"), 10, 64); err == nil {
+		event.Timestamp = time.UnixMilli(ms).UTC()
+	}
+	return event
+}
+
+func (r *RedisClickRepository) ClickStats(ctx context.Context, code, interval string, from, to time.Time) ([]model.StatsBucket, error) {
+	var starts []time.Time
+	for t := from.UTC(); !t.After(to); t = nextBucket(interval, t) {
+		starts = append(starts, t)
+	}
+
+	cmds := make([]*redisv9.SliceCmd, len(starts))
+	uvCmds := make([]*redisv9.IntCmd, len(starts))
+	_, err := r.rdb.Pipelined(ctx, func(pipe redisv9.Pipeliner) error {
+		for i, start := range starts {
+			cmds[i] = pipe.HMGet(ctx, statsKey(code, interval, start), "clicks", "bot_clicks", "conversions")
+			if interval == model.StatsIntervalDay {
+				uvCmds[i] = pipe.PFCount(ctx, uniqueVisitorsKey(code, start))
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, redisv9.Nil) {
+		return nil, err
+	}
+
+	buckets := make([]model.StatsBucket, len(starts))
+	for i, start := range starts {
+		buckets[i].Start = start
+		values := cmds[i].Val()
+		if len(values) == 3 {
+			buckets[i].Clicks = parseCount(values[0])
+			buckets[i].BotClicks = parseCount(values[1])
+			buckets[i].Conversions = parseCount(values[2])
+		}
+		if uvCmds[i] != nil {
+			uniques := uvCmds[i].Val()
+			buckets[i].Uniques = &uniques
+		}
+	}
+	return buckets, nil
+}
+
+func (r *RedisClickRepository) UniqueVisitors(ctx context.Context, code string) (int64, error) {
+	return r.rdb.PFCount(ctx, "shortener:uv:"+code).Result()
+}
+
+func (r *RedisClickRepository) UniqueVisitorsBetween(ctx context.Context, code string, from, to time.Time) (int64, error) {
+	from = from.UTC()
+	var keys []string
+	for t := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC); !t.After(to); t = t.AddDate(0, 0, 1) {
+		keys = append(keys, uniqueVisitorsKey(code, t))
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	// PFCOUNT 多个 key 时返回并集的基数，跨天访问的同一访客只计一次
+	return r.rdb.PFCount(ctx, keys...).Result()
+}
+
+func (r *RedisClickRepository) Breakdown(ctx context.Context, code, dimension string, limit int) ([]model.BreakdownItem, int64, error) {
+	key := breakdownKey(code, dimension)
+	top, err := r.rdb.ZRevRangeWithScores(ctx, key, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+	// 总数取自各取值之和（淘汰的长尾取值不再计入）
+	all, err := r.rdb.ZRangeWithScores(ctx, key, 0, -1).Result()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items := make([]model.BreakdownItem, 0, len(top))
+	for _, z := range top {
+		items = append(items, model.BreakdownItem{Value: z.Member.(string), Clicks: int64(z.Score)})
+	}
+	var total int64
+	for _, z := range all {
+		total += int64(z.Score)
+	}
+	return items, total, nil
+}
+
+// parseCount 解析 HMGET 返回的计数字段，字段不存在时为 0
+func parseCount(v interface{}) int64 {
+	str, ok := v.(string)
+	if !ok {
+		return 0
+	}
+	n, _ := strconv.ParseInt(str, 10, 64)
+	return n
+}
+
+func breakdownKey(code, dimension string) string {
+	return "shortener:breakdown:" + code + ":" + dimension
+}
+
+// uniqueVisitorsKey 返回时间 t 所在天（UTC）的独立访客 HyperLogLog key
+func uniqueVisitorsKey(code string, t time.Time) string {
+	return "shortener:uv:" + code + ":day:" + t.UTC().Format("20060102")
+}
+
+// statsKey 返回时间 t 所在统计桶的 key（按 UTC 对齐）
+func statsKey(code, interval string, t time.Time) string {
+	return "shortener:stats:" + code + ":" + statsKeySuffix(interval, t)
+}
+
+// statsKeySuffix 返回统计桶 key 中短码之后的部分（{interval}:{bucket}），供 Lua 脚本拼接 key
+func statsKeySuffix(interval string, t time.Time) string {
+	layout := "20060102"
+	if interval == model.StatsIntervalHour {
+		layout = "2006010215"
+	}
+	return interval + ":" + t.UTC().Format(layout)
+}
+
+// nextBucket 返回下一个桶的起始时间
+func nextBucket(interval string, t time.Time) time.Time {
+	if interval == model.StatsIntervalHour {
+		return t.Add(time.Hour)
+	}
+	return t.AddDate(0, 0, 1)
+}
@@ -0,0 +1,56 @@
+package redis
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"url-shortener/backend/internal/model"
+)
+
+func TestRetainedDailyKeys(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 30, 0, 0, time.UTC)
+	keys := retainedDailyKeys("abc123", now)
+
+	want := []string{
+		statsKey("abc123", model.StatsIntervalHour, now),
+		statsKey("abc123", model.StatsIntervalHour, now.Add(-hourlyStatsRetention)),
+		statsKey("abc123", model.StatsIntervalDay, now),
+		statsKey("abc123", model.StatsIntervalDay, now.Add(-dailyStatsRetention)),
+		uniqueVisitorsKey("abc123", now),
+		uniqueVisitorsKey("abc123", now.Add(-dailyStatsRetention)),
+	}
+	for _, key := range want {
+		if !slices.Contains(keys, key) {
+			t.Errorf("retainedDailyKeys() is missing %q", key)
+		}
+	}
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			t.Fatalf("retainedDailyKeys() contains %q more than once", key)
+		}
+		seen[key] = true
+	}
+}
+
+func TestRetainedLeaderboardKeys(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 32, 0, 0, time.UTC)
+	keys := retainedLeaderboardKeys(now)
+
+	for _, slot := range []leaderboardSlot{slot5m, slot1h} {
+		for _, at := range []time.Time{now, now.Add(-slot.retention)} {
+			if key := leaderboardKey(slot, at); !slices.Contains(keys, key) {
+				t.Errorf("retainedLeaderboardKeys() is missing %q", key)
+			}
+		}
+	}
+	// 所有窗口合并的分片都必须被覆盖
+	for window, w := range leaderboardWindows {
+		for i := 0; i < w.count; i++ {
+			if key := leaderboardKey(w.slot, now.Add(-time.Duration(i)*w.slot.width)); !slices.Contains(keys, key) {
+				t.Errorf("retainedLeaderboardKeys() is missing %q of window %s", key, window)
+			}
+		}
+	}
+}
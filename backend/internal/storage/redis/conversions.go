@@ -0,0 +1,126 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	redisv9 "github.com/redis/go-redis/v9"
+
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/storage"
+)
+
+func (r *RedisClickRepository) CreateAttribution(ctx context.Context, attr *model.Attribution, ttl time.Duration) error {
+	key := attributionKey(attr.Token)
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redisv9.Pipeliner) error {
+		pipe.HSet(ctx, key,
+			"code", attr.Code,
+			"variant", attr.Variant,
+			"ts", strconv.FormatInt(attr.ClickedAt.UnixMilli(), 10),
+		)
+		pipe.Expire(ctx, key, ttl)
+		return nil
+	})
+	return err
+}
+
+// maxGoalsPerToken 每个归因令牌最多记录的转化目标数，避免用一个令牌刷出任意多的目标
+const maxGoalsPerToken = 10
+
+// recordConversionScript 原子地校验令牌、去重并累加转化统计；
+// 令牌不存在时不写入任何数据，也就不会在令牌恰好过期时留下无 TTL 的 hash
+//
+// KEYS[1]: 归因令牌 key
+// ARGV[1]: 目标名；ARGV[2]: 转化时间（Unix 毫秒）；ARGV[3] / ARGV[4]: 小时桶 / 天桶后缀；
+// ARGV[5] / ARGV[6]: 小时桶 / 天桶保留秒数；ARGV[7]: 金额；ARGV[8]: 每个令牌最多的目标数
+// 返回：{状态, code, variant}，状态 1 表示已记录；0 表示重复；-2 表示令牌不存在；-3 表示目标数已达上限
+var recordConversionScript = redisv9.NewScript(`
+local attr = redis.call('HMGET', KEYS[1], 'code', 'variant', 'goals')
+local code = attr[1]
+if not code then
+	return {-2}
+end
+local variant = attr[2] or ''
+local field = 'goal:' .. ARGV[1]
+if redis.call('HEXISTS', KEYS[1], field) == 1 then
+	return {0, code, variant}
+end
+if (tonumber(attr[3]) or 0) >= tonumber(ARGV[8]) then
+	return {-3, code, variant}
+end
+redis.call('HSET', KEYS[1], field, ARGV[2])
+redis.call('HINCRBY', KEYS[1], 'goals', 1)
+
+local hourKey = 'shortener:stats:' .. code .. ':' .. ARGV[3]
+local dayKey = 'shortener:stats:' .. code .. ':' .. ARGV[4]
+redis.call('HINCRBY', hourKey, 'conversions', 1)
+redis.call('EXPIRE', hourKey, ARGV[5])
+redis.call('HINCRBY', dayKey, 'conversions', 1)
+redis.call('EXPIRE', dayKey, ARGV[6])
+
+local totalsKey = 'shortener:conv:' .. code
+redis.call('HINCRBY', totalsKey, 'total', 1)
+redis.call('HINCRBY', totalsKey, field, 1)
+if tonumber(ARGV[7]) > 0 then
+	redis.call('HINCRBYFLOAT', totalsKey, 'value:' .. ARGV[1], ARGV[7])
+end
+return {1, code, variant}
+`)
+
+func (r *RedisClickRepository) RecordConversion(ctx context.Context, conv *model.Conversion) (bool, error) {
+	if conv.Timestamp.IsZero() {
+		conv.Timestamp = time.Now().UTC()
+	}
+	res, err := recordConversionScript.Run(ctx, r.rdb, []string{attributionKey(conv.Token)},
+		conv.Goal,
+		conv.Timestamp.UnixMilli(),
+		statsKeySuffix(model.StatsIntervalHour, conv.Timestamp),
+		statsKeySuffix(model.StatsIntervalDay, conv.Timestamp),
+		int64(hourlyStatsRetention/time.Second),
+		int64(dailyStatsRetention/time.Second),
+		strconv.FormatFloat(conv.Value, 'f', -1, 64),
+		maxGoalsPerToken,
+	).Slice()
+	if err != nil {
+		return false, err
+	}
+
+	status, _ := res[0].(int64)
+	if status == -2 {
+		return false, storage.ErrAttributionNotFound
+	}
+	conv.Code, _ = res[1].(string)
+	conv.Variant, _ = res[2].(string)
+	switch status {
+	case -3:
+		return false, storage.ErrTooManyGoals
+	case 0:
+		return false, nil
+	}
+	return true, nil
+}
+
+func (r *RedisClickRepository) ConversionTotals(ctx context.Context, code string) (int64, map[string]int64, error) {
+	fields, err := r.rdb.HGetAll(ctx, "shortener:conv:"+code).Result()
+	if err != nil {
+		return 0, nil, err
+	}
+	var total int64
+	goals := make(map[string]int64)
+	for field, value := range fields {
+		n, _ := strconv.ParseInt(value, 10, 64)
+		switch {
+		case field == "total":
+			total = n
+		case strings.HasPrefix(field, "goal:"):
+			goals[strings.TrimPrefix(field, "goal:")] = n
+		}
+	}
+	return total, goals, nil
+}
+
+func attributionKey(token string) string {
+	return "shortener:attr:" + token
+}
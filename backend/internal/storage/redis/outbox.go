@@ -0,0 +1,212 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	redisv9 "github.com/redis/go-redis/v9"
+
+	"url-shortener/backend/internal/model"
+)
+
+const (
+	// outboxKey 领域事件 outbox (stream)，fields: type, code, ts (Unix 毫秒), data (JSON)。
+	// 写入时不裁剪，由 TrimOutbox 删除所有 sink 都已确认的事件，PurgeOutbox 删除超过保留期的事件
+	outboxKey = "shortener:outbox"
+	// outboxClaimIdle 读取后超过该时长仍未确认的事件会重新分配
+	outboxClaimIdle = time.Minute
+)
+
+// outboxLua 供其他脚本复用的 outbox 写入函数：
+// 从 argv[offset] 开始依次为事件数 n 和 n 组 (type, code, ts, data)，返回之后第一个参数的下标
+var outboxLua = `
+local function append_outbox(key, argv, offset)
+	local n = tonumber(argv[offset])
+	for i = 0, n - 1 do
+		local base = offset + 1 + i * 4
+		redis.call('XADD', key, '*', 'type', argv[base], 'code', argv[base + 1], 'ts', argv[base + 2], 'data', argv[base + 3])
+	end
+	return offset + 1 + n * 4
+end
+`
+
+// outboxArgs 将事件编码为 append_outbox 的参数
+func outboxArgs(events []*model.DomainEvent) []any {
+	args := make([]any, 0, 1+len(events)*4)
+	args = append(args, len(events))
+	for _, e := range events {
+		args = append(args, e.Type, e.Code, strconv.FormatInt(e.OccurredAt.UnixMilli(), 10), string(e.Data))
+	}
+	return args
+}
+
+// addOutbox 在事务管道中写入事件（用于不需要脚本的写操作）
+func addOutbox(ctx context.Context, pipe redisv9.Pipeliner, events []*model.DomainEvent) {
+	for _, e := range events {
+		pipe.XAdd(ctx, &redisv9.XAddArgs{
+			Stream: outboxKey,
+			Values: []any{
+				"type", e.Type,
+				"code", e.Code,
+				"ts", strconv.FormatInt(e.OccurredAt.UnixMilli(), 10),
+				"data", string(e.Data),
+			},
+		})
+	}
+}
+
+func (r *RedisRepository) ReadOutbox(ctx context.Context, group, consumer string, count int, block time.Duration) ([]*model.DomainEvent, error) {
+	messages, err := r.readOutbox(ctx, group, consumer, count, block)
+	// 消费组不存在时创建（从 outbox 中最早的事件开始）后重试
+	if err != nil && strings.HasPrefix(err.Error(), "NOGROUP") {
+		if err := r.rdb.XGroupCreateMkStream(ctx, outboxKey, group, "0").Err(); err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return nil, err
+		}
+		messages, err = r.readOutbox(ctx, group, consumer, count, block)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*model.DomainEvent, 0, len(messages))
+	for _, msg := range messages {
+		events = append(events, parseOutboxEntry(msg))
+	}
+	return events, nil
+}
+
+// readOutbox 先认领超时未确认的事件，没有时再读取新事件
+func (r *RedisRepository) readOutbox(ctx context.Context, group, consumer string, count int, block time.Duration) ([]redisv9.XMessage, error) {
+	claimed, _, err := r.rdb.XAutoClaim(ctx, &redisv9.XAutoClaimArgs{
+		Stream:   outboxKey,
+		Group:    group,
+		Consumer: consumer,
+		MinIdle:  outboxClaimIdle,
+		Start:    "0-0",
+		Count:    int64(count),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(claimed) > 0 {
+		return claimed, nil
+	}
+
+	streams, err := r.rdb.XReadGroup(ctx, &redisv9.XReadGroupArgs{
+		Group:    group,
+		Consumer: consumer,
+		Streams:  []string{outboxKey, ">"},
+		Count:    int64(count),
+		Block:    block,
+	}).Result()
+	if err != nil {
+		if errors.Is(err, redisv9.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(streams) == 0 {
+		return nil, nil
+	}
+	return streams[0].Messages, nil
+}
+
+func (r *RedisRepository) AckOutbox(ctx context.Context, group string, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.rdb.XAck(ctx, outboxKey, group, ids...).Err()
+}
+
+func (r *RedisRepository) TrimOutbox(ctx context.Context, groups []string) (int64, error) {
+	infos, err := r.rdb.XInfoGroups(ctx, outboxKey).Result()
+	if err != nil {
+		if strings.Contains(err.Error(), "no such key") {
+			return 0, nil
+		}
+		return 0, err
+	}
+	byName := make(map[string]redisv9.XInfoGroup, len(infos))
+	for _, info := range infos {
+		byName[info.Name] = info
+	}
+
+	// 每个消费组已确认的位置：有未确认事件时为最早的未确认事件，否则为最后读取的事件；
+	// 取所有消费组中最小的位置，早于它的事件都已被所有 sink 确认
+	minID := ""
+	for _, group := range groups {
+		info, ok := byName[group]
+		if !ok {
+			// sink 尚未开始读取（消费组会从最早的事件开始），不能裁剪
+			return 0, nil
+		}
+		id := info.LastDeliveredID
+		if info.Pending > 0 {
+			pending, err := r.rdb.XPending(ctx, outboxKey, group).Result()
+			if err != nil {
+				return 0, err
+			}
+			id = pending.Lower
+		}
+		if minID == "" || compareStreamID(id, minID) < 0 {
+			minID = id
+		}
+	}
+	if minID == "" || minID == "0-0" {
+		return 0, nil
+	}
+	return r.rdb.XTrimMinID(ctx, outboxKey, minID).Result()
+}
+
+func (r *RedisRepository) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	// 精确裁剪（非近似），保证超过保留期的事件都被删除
+	return r.rdb.XTrimMinID(ctx, outboxKey, strconv.FormatInt(before.UnixMilli(), 10)).Result()
+}
+
+// compareStreamID 比较两个 stream entry ID（{毫秒}-{序号}）的先后
+func compareStreamID(a, b string) int {
+	parse := func(id string) (uint64, uint64) {
+		ms, seq, _ := strings.Cut(id, "-")
+		m, _ := strconv.ParseUint(ms, 10, 64)
+		n, _ := strconv.ParseUint(seq, 10, 64)
+		return m, n
+	}
+	am, as := parse(a)
+	bm, bs := parse(b)
+	switch {
+	case am != bm:
+		if am < bm {
+			return -1
+		}
+		return 1
+	case as != bs:
+		if as < bs {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// parseOutboxEntry 将 outbox 中的一条记录还原为领域事件
+func parseOutboxEntry(msg redisv9.XMessage) *model.DomainEvent {
+	field := func(name string) string {
+		v, _ := msg.Values[name].(string)
+		return v
+	}
+	event := &model.DomainEvent{
+		ID:   msg.ID,
+		Type: field("type"),
+		Code: field("code"),
+	}
+	if data := field("data"); data != "" {
+		event.Data = []byte(data)
+	}
+	if ms, err := strconv.ParseInt(field("ts"), 10, 64); err == nil {
+		event.OccurredAt = time.UnixMilli(ms).UTC()
+	}
+	return event
+}
@@ -2,7 +2,9 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"strconv"
+	"strings"
 	"time"
 
 	redisv9 "github.com/redis/go-redis/v9"
@@ -14,13 +16,28 @@ import (
 // RedisRepository 使用 Redis 存储短链接数据
 //
 // Key 设计：
-// - 全局自增：shortener:next_id (string)
-// - 记录：shortener:link:{code} (hash)
-//   fields: id, code, long_url, created_at, expire_at, click_count, last_accessed_at
+//   - 全局自增：shortener:next_id (string)
+//   - 记录：shortener:link:{code} (hash)
+//     fields: id, code, long_url, created_at, expire_at, click_count, bot_click_count, last_accessed_at,
+//     destinations (JSON), routing_mode, rr_counter, sticky_variant, variant_clicks:{id},
+//     health:{id} (JSON), language_urls (JSON), health:lang:{tag} (JSON),
+//     activate_at, schedule (JSON), fallback_url, inactive_message, max_clicks, password_hash,
+//     force_preview, disabled, og_title, og_description, og_image, metadata (JSON),
+//     app_url, ios_store_url, android_store_url, hide_referrer, track_conversions, health (JSON)
+//   - 密码尝试计数：shortener:pwd_attempts:{code} (string，带窗口 TTL)
+//   - 失效链接集合：shortener:broken (set，健康检查发现任一目标不可用的短码)
+//   - 领域事件 outbox：shortener:outbox (stream，与触发事件的写操作在同一事务中追加，见 outbox.go)
+//   - 过期队列：shortener:expiring (sorted set，member 为设置了 expire_at 的短码，score 为过期时间 Unix 秒，
+//     领取后为租约截止时间)；shortener:expiring:backfilled (string，历史链接已补录的标记)
+//
+// 设置了 expire_at 的记录会在过期后继续保留 expiredLinkRetention，以便区分“已过期”与“不存在”
 type RedisRepository struct {
 	rdb *redisv9.Client
 }
 
+// expiredLinkRetention 过期记录的保留时长
+const expiredLinkRetention = 30 * 24 * time.Hour
+
 func NewRepository(rdb *redisv9.Client) storage.LinkRepository {
 	return &RedisRepository{rdb: rdb}
 }
@@ -29,7 +46,7 @@ func (r *RedisRepository) NextID(ctx context.Context) (int64, error) {
 	return r.rdb.Incr(ctx, "shortener:next_id").Result()
 }
 
-func (r *RedisRepository) Create(ctx context.Context, link *model.ShortLink) (*model.ShortLink, error) {
+func (r *RedisRepository) Create(ctx context.Context, link *model.ShortLink, events ...*model.DomainEvent) (*model.ShortLink, error) {
 	key := "shortener:link:" + link.Code
 
 	// 保证 created_at 非空
@@ -37,14 +54,9 @@ func (r *RedisRepository) Create(ctx context.Context, link *model.ShortLink) (*m
 		link.CreatedAt = time.Now().UTC()
 	}
 
-	createdAt := link.CreatedAt.UTC().Format(time.RFC3339Nano)
-	expireAt := ""
-	if link.ExpireAt != nil {
-		expireAt = link.ExpireAt.UTC().Format(time.RFC3339Nano)
-	}
-	lastAccessed := ""
-	if link.LastAccessedAt != nil {
-		lastAccessed = link.LastAccessedAt.UTC().Format(time.RFC3339Nano)
+	fields, err := linkFields(link)
+	if err != nil {
+		return nil, err
 	}
 
 	// 如果 key 已存在则返回冲突（上层按 duplicate 处理）
@@ -57,23 +69,10 @@ func (r *RedisRepository) Create(ctx context.Context, link *model.ShortLink) (*m
 	}
 
 	pipe := r.rdb.TxPipeline()
-	pipe.HSet(ctx, key, map[string]any{
-		"id":               link.ID,
-		"code":             link.Code,
-		"long_url":         link.LongURL,
-		"created_at":       createdAt,
-		"expire_at":        expireAt,
-		"click_count":      link.ClickCount,
-		"last_accessed_at": lastAccessed,
-	})
+	pipe.HSet(ctx, key, fields)
 
-	// 设置 TTL：如果有 expire_at，则 key TTL = expire_at - now（过期后自动失效）
-	if link.ExpireAt != nil {
-		ttl := time.Until(link.ExpireAt.UTC())
-		if ttl > 0 {
-			pipe.Expire(ctx, key, ttl)
-		}
-	}
+	setExpiry(ctx, pipe, link.Code, link.ExpireAt)
+	addOutbox(ctx, pipe, events)
 
 	_, err = pipe.Exec(ctx)
 	if err != nil {
@@ -82,6 +81,130 @@ func (r *RedisRepository) Create(ctx context.Context, link *model.ShortLink) (*m
 	return link, nil
 }
 
+// setExpiry 设置记录的 TTL 并维护过期队列：有 expire_at 时 key TTL = expire_at - now + 保留期
+// （保留期结束后自动删除），否则取消 TTL
+func setExpiry(ctx context.Context, pipe redisv9.Pipeliner, code string, expireAt *time.Time) {
+	key := "shortener:link:" + code
+	if expireAt == nil {
+		pipe.Persist(ctx, key)
+		pipe.ZRem(ctx, "shortener:expiring", code)
+		return
+	}
+	ttl := time.Until(expireAt.UTC()) + expiredLinkRetention
+	if ttl > 0 {
+		pipe.Expire(ctx, key, ttl)
+	}
+	pipe.ZAdd(ctx, "shortener:expiring", redisv9.Z{Score: float64(expireAt.Unix()), Member: code})
+}
+
+func (r *RedisRepository) Update(ctx context.Context, link *model.ShortLink, events ...*model.DomainEvent) error {
+	expireAt := ""
+	if link.ExpireAt != nil {
+		expireAt = link.ExpireAt.UTC().Format(time.RFC3339Nano)
+	}
+	err := r.setFieldsIfExists(ctx, link.Code, events,
+		"long_url", link.LongURL,
+		"expire_at", expireAt,
+		"fallback_url", link.FallbackURL,
+		"inactive_message", link.InactiveMessage,
+		"max_clicks", link.MaxClicks,
+	)
+	if err != nil {
+		return err
+	}
+	_, err = r.rdb.TxPipelined(ctx, func(pipe redisv9.Pipeliner) error {
+		setExpiry(ctx, pipe, link.Code, link.ExpireAt)
+		return nil
+	})
+	return err
+}
+
+// deleteLinkScript 删除记录及其附属 key，并写入事件；记录不存在时不做任何修改
+//
+// KEYS[1]: 记录 key；KEYS[2]: 密码尝试计数；KEYS[3]: 失效链接集合；KEYS[4]: 过期队列；KEYS[5]: outbox
+// ARGV[1]: 短码；ARGV[2...]: outbox 事件
+// 返回：1 表示已删除；0 表示记录不存在
+var deleteLinkScript = redisv9.NewScript(outboxLua + `
+if redis.call('DEL', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('DEL', KEYS[2])
+redis.call('SREM', KEYS[3], ARGV[1])
+redis.call('ZREM', KEYS[4], ARGV[1])
+append_outbox(KEYS[5], ARGV, 2)
+return 1
+`)
+
+func (r *RedisRepository) Delete(ctx context.Context, code string, events ...*model.DomainEvent) error {
+	keys := []string{"shortener:link:" + code, "shortener:pwd_attempts:" + code, "shortener:broken", "shortener:expiring", outboxKey}
+	args := append([]any{code}, outboxArgs(events)...)
+	deleted, err := deleteLinkScript.Run(ctx, r.rdb, keys, args...).Int64()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return storage.ErrLinkNotFound
+	}
+	return nil
+}
+
+// claimExpiredScript 领取已到期的短码：把它们的 score 改为租约截止时间，
+// 多个实例同时轮询时每个短码在租约内只会被领取一次
+//
+// KEYS[1]: 过期队列
+// ARGV[1]: 当前时间（Unix 秒）；ARGV[2]: 数量上限；ARGV[3]: 租约截止时间（Unix 秒）
+var claimExpiredScript = redisv9.NewScript(`
+local codes = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, code in ipairs(codes) do
+	redis.call('ZADD', KEYS[1], ARGV[3], code)
+end
+return codes
+`)
+
+func (r *RedisRepository) ClaimExpired(ctx context.Context, now, until time.Time, limit int) ([]string, error) {
+	return claimExpiredScript.Run(ctx, r.rdb, []string{"shortener:expiring"}, now.Unix(), limit, until.Unix()).StringSlice()
+}
+
+// ackExpiredScript 仅当短码的 score 仍为租约截止时间时移出过期队列并写入事件
+//
+// KEYS[1]: 过期队列；KEYS[2]: outbox
+// ARGV[1]: 短码；ARGV[2]: 租约截止时间（Unix 秒）；ARGV[3...]: outbox 事件
+var ackExpiredScript = redisv9.NewScript(outboxLua + `
+local score = redis.call('ZSCORE', KEYS[1], ARGV[1])
+if not score or tonumber(score) ~= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('ZREM', KEYS[1], ARGV[1])
+append_outbox(KEYS[2], ARGV, 3)
+return 1
+`)
+
+func (r *RedisRepository) AckExpired(ctx context.Context, code string, until time.Time, events ...*model.DomainEvent) error {
+	args := append([]any{code, until.Unix()}, outboxArgs(events)...)
+	return ackExpiredScript.Run(ctx, r.rdb, []string{"shortener:expiring", outboxKey}, args...).Err()
+}
+
+// expiringBackfilledKey 标记过期队列已补录完成
+const expiringBackfilledKey = "shortener:expiring:backfilled"
+
+func (r *RedisRepository) BackfillExpiring(ctx context.Context, now time.Time) error {
+	done, err := r.rdb.Exists(ctx, expiringBackfilledKey).Result()
+	if err != nil || done == 1 {
+		return err
+	}
+	// 已经过期的历史链接不补发通知；ZADD NX 不会覆盖已入队（或已被领取）的短码，多个实例同时补录也无妨
+	err = r.ForEachLink(ctx, func(link *model.ShortLink) error {
+		if link.ExpireAt == nil || !link.ExpireAt.After(now) {
+			return nil
+		}
+		return r.rdb.ZAddNX(ctx, "shortener:expiring", redisv9.Z{Score: float64(link.ExpireAt.Unix()), Member: link.Code}).Err()
+	})
+	if err != nil {
+		return err
+	}
+	return r.rdb.Set(ctx, expiringBackfilledKey, now.UTC().Format(time.RFC3339), 0).Err()
+}
+
 func (r *RedisRepository) GetByCode(ctx context.Context, code string) (*model.ShortLink, error) {
 	key := "shortener:link:" + code
 	m, err := r.rdb.HGetAll(ctx, key).Result()
@@ -92,8 +215,15 @@ func (r *RedisRepository) GetByCode(ctx context.Context, code string) (*model.Sh
 		return nil, nil
 	}
 
+	return parseLink(m), nil
+}
+
+// parseLink 从记录 hash 的字段解码链接，无法解析的字段保持零值
+func parseLink(m map[string]string) *model.ShortLink {
 	id, _ := strconv.ParseInt(m["id"], 10, 64)
 	clickCount, _ := strconv.ParseInt(m["click_count"], 10, 64)
+	botClickCount, _ := strconv.ParseInt(m["bot_click_count"], 10, 64)
+	maxClicks, _ := strconv.ParseInt(m["max_clicks"], 10, 64)
 
 	var createdAt time.Time
 	if m["created_at"] != "" {
@@ -108,6 +238,14 @@ func (r *RedisRepository) GetByCode(ctx context.Context, code string) (*model.Sh
 		}
 	}
 
+	var activateAt *time.Time
+	if m["activate_at"] != "" {
+		t, err := time.Parse(time.RFC3339Nano, m["activate_at"])
+		if err == nil {
+			activateAt = &t
+		}
+	}
+
 	var lastAccessedAt *time.Time
 	if m["last_accessed_at"] != "" {
 		t, err := time.Parse(time.RFC3339Nano, m["last_accessed_at"])
@@ -116,24 +254,384 @@ func (r *RedisRepository) GetByCode(ctx context.Context, code string) (*model.Sh
 		}
 	}
 
+	var destinations []model.Destination
+	if m["destinations"] != "" {
+		if err := json.Unmarshal([]byte(m["destinations"]), &destinations); err == nil {
+			for i := range destinations {
+				destinations[i].ClickCount, _ = strconv.ParseInt(m["variant_clicks:"+destinations[i].ID], 10, 64)
+				destinations[i].Health = parseHealth(m["health:"+destinations[i].ID])
+			}
+		}
+	}
+
+	var languageURLs map[string]string
+	var languageHealth map[string]*model.HealthStatus
+	if m["language_urls"] != "" {
+		_ = json.Unmarshal([]byte(m["language_urls"]), &languageURLs)
+		for tag := range languageURLs {
+			if health := parseHealth(m["health:"+model.LanguageHealthID(tag)]); health != nil {
+				if languageHealth == nil {
+					languageHealth = make(map[string]*model.HealthStatus, len(languageURLs))
+				}
+				languageHealth[tag] = health
+			}
+		}
+	}
+
+	var metadata *model.PageMetadata
+	if m["metadata"] != "" {
+		metadata = &model.PageMetadata{}
+		if err := json.Unmarshal([]byte(m["metadata"]), metadata); err != nil {
+			metadata = nil
+		}
+	}
+
+	var schedule *model.Schedule
+	if m["schedule"] != "" {
+		schedule = &model.Schedule{}
+		if err := json.Unmarshal([]byte(m["schedule"]), schedule); err != nil {
+			schedule = nil
+		}
+	}
+
 	return &model.ShortLink{
-		ID:             id,
-		Code:           m["code"],
-		LongURL:        m["long_url"],
-		CreatedAt:      createdAt,
-		ExpireAt:       expireAt,
-		ClickCount:     clickCount,
-		LastAccessedAt: lastAccessedAt,
+		ID:               id,
+		Code:             m["code"],
+		LongURL:          m["long_url"],
+		CreatedAt:        createdAt,
+		ExpireAt:         expireAt,
+		ClickCount:       clickCount,
+		BotClickCount:    botClickCount,
+		LastAccessedAt:   lastAccessedAt,
+		Destinations:     destinations,
+		RoutingMode:      m["routing_mode"],
+		StickyVariant:    m["sticky_variant"] == "1",
+		LanguageURLs:     languageURLs,
+		ActivateAt:       activateAt,
+		Schedule:         schedule,
+		FallbackURL:      m["fallback_url"],
+		InactiveMessage:  m["inactive_message"],
+		MaxClicks:        maxClicks,
+		PasswordHash:     m["password_hash"],
+		ForcePreview:     m["force_preview"] == "1",
+		Disabled:         m["disabled"] == "1",
+		OGTitle:          m["og_title"],
+		OGDescription:    m["og_description"],
+		OGImage:          m["og_image"],
+		Metadata:         metadata,
+		Health:           parseHealth(m["health"]),
+		LanguageHealth:   languageHealth,
+		AppURL:           m["app_url"],
+		IOSStoreURL:      m["ios_store_url"],
+		AndroidStoreURL:  m["android_store_url"],
+		HideReferrer:     m["hide_referrer"] == "1",
+		TrackConversions: m["track_conversions"] == "1",
+	}
+}
+
+// linkFields 把链接编码为记录 hash 的字段（计数器、健康状态等由各自的操作单独维护）
+func linkFields(link *model.ShortLink) (map[string]any, error) {
+	createdAt := link.CreatedAt.UTC().Format(time.RFC3339Nano)
+	expireAt := ""
+	if link.ExpireAt != nil {
+		expireAt = link.ExpireAt.UTC().Format(time.RFC3339Nano)
+	}
+	activateAt := ""
+	if link.ActivateAt != nil {
+		activateAt = link.ActivateAt.UTC().Format(time.RFC3339Nano)
+	}
+	lastAccessed := ""
+	if link.LastAccessedAt != nil {
+		lastAccessed = link.LastAccessedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	destinations := ""
+	if len(link.Destinations) > 0 {
+		data, err := json.Marshal(link.Destinations)
+		if err != nil {
+			return nil, err
+		}
+		destinations = string(data)
+	}
+	languageURLs := ""
+	if len(link.LanguageURLs) > 0 {
+		data, err := json.Marshal(link.LanguageURLs)
+		if err != nil {
+			return nil, err
+		}
+		languageURLs = string(data)
+	}
+	schedule := ""
+	if link.Schedule != nil {
+		data, err := json.Marshal(link.Schedule)
+		if err != nil {
+			return nil, err
+		}
+		schedule = string(data)
+	}
+
+	return map[string]any{
+		"id":                link.ID,
+		"code":              link.Code,
+		"long_url":          link.LongURL,
+		"created_at":        createdAt,
+		"expire_at":         expireAt,
+		"click_count":       link.ClickCount,
+		"last_accessed_at":  lastAccessed,
+		"destinations":      destinations,
+		"routing_mode":      link.RoutingMode,
+		"sticky_variant":    formatBool(link.StickyVariant),
+		"language_urls":     languageURLs,
+		"activate_at":       activateAt,
+		"schedule":          schedule,
+		"fallback_url":      link.FallbackURL,
+		"inactive_message":  link.InactiveMessage,
+		"max_clicks":        link.MaxClicks,
+		"password_hash":     link.PasswordHash,
+		"force_preview":     formatBool(link.ForcePreview),
+		"disabled":          formatBool(link.Disabled),
+		"og_title":          link.OGTitle,
+		"og_description":    link.OGDescription,
+		"og_image":          link.OGImage,
+		"app_url":           link.AppURL,
+		"ios_store_url":     link.IOSStoreURL,
+		"android_store_url": link.AndroidStoreURL,
+		"hide_referrer":     formatBool(link.HideReferrer),
+		"track_conversions": formatBool(link.TrackConversions),
 	}, nil
 }
 
-func (r *RedisRepository) IncrementClick(ctx context.Context, code string) error {
+// clicksExhaustedLua 判断记录是否已用完 max_clicks：人工点击与机器人访问都占用次数
+const clicksExhaustedLua = `
+local function clicks_exhausted(key)
+	local max = tonumber(redis.call('HGET', key, 'max_clicks') or '0') or 0
+	if max <= 0 then
+		return false
+	end
+	local used = (tonumber(redis.call('HGET', key, 'click_count') or '0') or 0) +
+		(tonumber(redis.call('HGET', key, 'bot_click_count') or '0') or 0)
+	return used >= max
+end
+`
+
+// incrementClickScript 原子地检查 max_clicks 并累加点击次数
+//
+// KEYS[1]: 记录 key；KEYS[2]: outbox
+// ARGV[1]: 当前时间；ARGV[2]: A/B 分组点击字段（可为空）；ARGV[3...]: outbox 事件（仅在累加成功时写入）
+// 返回：累加后的点击次数；-1 表示已达上限；-2 表示记录不存在
+var incrementClickScript = redisv9.NewScript(outboxLua + clicksExhaustedLua + `
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -2
+end
+if clicks_exhausted(KEYS[1]) then
+	return -1
+end
+local count = redis.call('HINCRBY', KEYS[1], 'click_count', 1)
+if ARGV[2] ~= '' then
+	redis.call('HINCRBY', KEYS[1], ARGV[2], 1)
+end
+redis.call('HSET', KEYS[1], 'last_accessed_at', ARGV[1])
+append_outbox(KEYS[2], ARGV, 3)
+return count
+`)
+
+func (r *RedisRepository) IncrementClick(ctx context.Context, code, variant string, events ...*model.DomainEvent) (int64, error) {
 	key := "shortener:link:" + code
 	now := time.Now().UTC().Format(time.RFC3339Nano)
+	variantField := ""
+	if variant != "" {
+		variantField = "variant_clicks:" + variant
+	}
+
+	args := append([]any{now, variantField}, outboxArgs(events)...)
+	count, err := incrementClickScript.Run(ctx, r.rdb, []string{key, outboxKey}, args...).Int64()
+	if err != nil {
+		return 0, err
+	}
+	switch count {
+	case -1:
+		return 0, storage.ErrClicksExhausted
+	case -2:
+		return 0, storage.ErrLinkNotFound
+	}
+	return count, nil
+}
+
+// incrementFieldScript 仅在记录存在时累加计数字段，避免为已删除或已过期的短码生成残缺记录
+//
+// KEYS[1]: 记录 key；ARGV[1]: 字段名
+// 返回：累加后的值；-2 表示记录不存在
+var incrementFieldScript = redisv9.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -2
+end
+return redis.call('HINCRBY', KEYS[1], ARGV[1], 1)
+`)
+
+// incrementField 累加记录中的计数字段，记录不存在时返回 ErrLinkNotFound
+func (r *RedisRepository) incrementField(ctx context.Context, code, field string) (int64, error) {
+	n, err := incrementFieldScript.Run(ctx, r.rdb, []string{"shortener:link:" + code}, field).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if n == -2 {
+		return 0, storage.ErrLinkNotFound
+	}
+	return n, nil
+}
+
+// incrementBotClickScript 原子地检查 max_clicks 并累加机器人访问次数
+//
+// KEYS[1]: 记录 key
+// 返回：1 表示已累加；-1 表示已达到上限；-2 表示记录不存在
+var incrementBotClickScript = redisv9.NewScript(clicksExhaustedLua + `
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -2
+end
+if clicks_exhausted(KEYS[1]) then
+	return -1
+end
+redis.call('HINCRBY', KEYS[1], 'bot_click_count', 1)
+return 1
+`)
+
+func (r *RedisRepository) IncrementBotClick(ctx context.Context, code string) error {
+	n, err := incrementBotClickScript.Run(ctx, r.rdb, []string{"shortener:link:" + code}).Int64()
+	if err != nil {
+		return err
+	}
+	switch n {
+	case -1:
+		return storage.ErrClicksExhausted
+	case -2:
+		return storage.ErrLinkNotFound
+	}
+	return nil
+}
+
+// setFieldsIfExistsScript 仅在记录存在时更新字段，避免为已删除的短码生成残缺记录
+//
+// KEYS[1]: 记录 key；KEYS[2]: outbox；ARGV: outbox 事件，之后为 field1, value1, field2, value2...
+// 返回：1 表示已更新；0 表示记录不存在
+var setFieldsIfExistsScript = redisv9.NewScript(outboxLua + `
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+local first = append_outbox(KEYS[2], ARGV, 1)
+redis.call('HSET', KEYS[1], unpack(ARGV, first))
+return 1
+`)
+
+// setFieldsIfExists 更新已存在记录的字段并写入事件，记录不存在时返回 storage.ErrLinkNotFound
+func (r *RedisRepository) setFieldsIfExists(ctx context.Context, code string, events []*model.DomainEvent, fields ...any) error {
+	args := append(outboxArgs(events), fields...)
+	updated, err := setFieldsIfExistsScript.Run(ctx, r.rdb, []string{"shortener:link:" + code, outboxKey}, args...).Int64()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return storage.ErrLinkNotFound
+	}
+	return nil
+}
+
+func (r *RedisRepository) SetDisabled(ctx context.Context, code string, disabled bool, events ...*model.DomainEvent) error {
+	return r.setFieldsIfExists(ctx, code, events, "disabled", formatBool(disabled))
+}
+
+func (r *RedisRepository) UpdateMetadata(ctx context.Context, code string, metadata *model.PageMetadata) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return err
+	}
+	return r.setFieldsIfExists(ctx, code, nil, "metadata", string(data))
+}
+
+func (r *RedisRepository) NextRoundRobin(ctx context.Context, code string) (int64, error) {
+	return r.incrementField(ctx, code, "rr_counter")
+}
+
+func (r *RedisRepository) SetHealth(ctx context.Context, code, destinationID string, status *model.HealthStatus) error {
+	data, err := json.Marshal(status)
+	if err != nil {
+		return err
+	}
+	field := "health"
+	if destinationID != "" {
+		field = "health:" + destinationID
+	}
+	return r.setFieldsIfExists(ctx, code, nil, field, string(data))
+}
+
+func (r *RedisRepository) SetBroken(ctx context.Context, code string, broken bool) error {
+	if broken {
+		return r.rdb.SAdd(ctx, "shortener:broken", code).Err()
+	}
+	return r.rdb.SRem(ctx, "shortener:broken", code).Err()
+}
+
+func (r *RedisRepository) ListBroken(ctx context.Context) ([]string, error) {
+	return r.rdb.SMembers(ctx, "shortener:broken").Result()
+}
+
+// ForEachLink 使用 SCAN 增量遍历记录 key，不会长时间阻塞 Redis
+func (r *RedisRepository) ForEachLink(ctx context.Context, fn func(link *model.ShortLink) error) error {
+	const prefix = "shortener:link:"
+	iter := r.rdb.Scan(ctx, 0, prefix+"*", 200).Iterator()
+	for iter.Next(ctx) {
+		link, err := r.GetByCode(ctx, strings.TrimPrefix(iter.Val(), prefix))
+		if err != nil {
+			return err
+		}
+		// SCAN 期间被删除的记录直接跳过
+		if link == nil {
+			continue
+		}
+		if err := fn(link); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+func (r *RedisRepository) IncrementPasswordAttempts(ctx context.Context, code string, window time.Duration) (int64, error) {
+	key := "shortener:pwd_attempts:" + code
 	pipe := r.rdb.TxPipeline()
-	pipe.HIncrBy(ctx, key, "click_count", 1)
-	pipe.HSet(ctx, key, "last_accessed_at", now)
-	_, err := pipe.Exec(ctx)
-	return err
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
 }
 
+// decrementIfExistsScript 仅在计数 key 仍存在时递减，避免窗口过期后生成无 TTL 的负数 key
+var decrementIfExistsScript = redisv9.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return redis.call('DECR', KEYS[1])
+end
+return 0
+`)
+
+func (r *RedisRepository) DecrementPasswordAttempts(ctx context.Context, code string) error {
+	return decrementIfExistsScript.Run(ctx, r.rdb, []string{"shortener:pwd_attempts:" + code}).Err()
+}
+
+// parseHealth 解析健康检查结果字段，为空或格式错误时返回 nil
+func parseHealth(raw string) *model.HealthStatus {
+	if raw == "" {
+		return nil
+	}
+	health := &model.HealthStatus{}
+	if err := json.Unmarshal([]byte(raw), health); err != nil {
+		return nil
+	}
+	return health
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,65 @@
+package redis
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"url-shortener/backend/internal/model"
+)
+
+func TestLinkFieldsRoundTrip(t *testing.T) {
+	expireAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	link := &model.ShortLink{
+		ID:        42,
+		Code:      "mirrors",
+		LongURL:   "https://a.example.com",
+		CreatedAt: time.Date(2024, 1, 5, 9, 0, 0, 0, time.UTC),
+		ExpireAt:  &expireAt,
+		Destinations: []model.Destination{
+			{ID: "a", URL: "https://a.example.com", Weight: 1},
+			{ID: "b", URL: "https://b.example.com", Weight: 1},
+		},
+		RoutingMode:   model.RoutingFailover,
+		StickyVariant: true,
+		LanguageURLs:  map[string]string{"de": "https://de.example.com"},
+		Schedule: &model.Schedule{
+			Timezone: "Asia/Shanghai",
+			Windows:  []model.ScheduleWindow{{Days: []string{"weekdays"}, Start: "09:00", End: "18:00"}},
+		},
+		MaxClicks:    3,
+		PasswordHash: "hash",
+		HideReferrer: true,
+	}
+
+	fields, err := linkFields(link)
+	if err != nil {
+		t.Fatalf("linkFields: %v", err)
+	}
+	// Redis 以字符串保存所有字段
+	stored := make(map[string]string, len(fields))
+	for name, value := range fields {
+		stored[name] = fmt.Sprint(value)
+	}
+
+	got := parseLink(stored)
+	if !reflect.DeepEqual(got, link) {
+		t.Errorf("parseLink(linkFields(link)) = %+v, want %+v", got, link)
+	}
+}
+
+func TestParseLinkLanguageHealth(t *testing.T) {
+	stored := map[string]string{
+		"code":           "langs1",
+		"language_urls":  `{"de":"https://de.example.com","fr":"https://fr.example.com"}`,
+		"health:lang:de": `{"up":false,"status_code":404}`,
+		"health:lang:es": `{"up":true}`,
+	}
+
+	got := parseLink(stored).LanguageHealth
+	want := map[string]*model.HealthStatus{"de": {Up: false, StatusCode: 404}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LanguageHealth = %+v, want %+v", got, want)
+	}
+}
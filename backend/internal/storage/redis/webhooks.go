@@ -0,0 +1,315 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	redisv9 "github.com/redis/go-redis/v9"
+
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/storage"
+)
+
+const (
+	// deliveryQueueKey 待投递队列，member 为投递 ID，score 为下次可投递时间（Unix 毫秒）
+	deliveryQueueKey = "shortener:delivery:queue"
+	// deliveryLogSize 每个 webhook 保留的最近投递记录数
+	deliveryLogSize = 100
+	// deliveryRetention 投递完成（成功或放弃）后记录的保留时长
+	deliveryRetention = 7 * 24 * time.Hour
+)
+
+// RedisWebhookRepository 使用 Redis 保存 webhook 与投递队列
+//
+// Key 设计：
+//   - webhook：shortener:webhook:{id} (hash)
+//     fields: id, code, url, secret, events (逗号分隔), click_threshold, created_at
+//   - 短码的 webhook 列表：shortener:webhooks:{code} (sorted set，member 为 webhook ID，score 为创建时间)
+//   - 投递记录：shortener:delivery:{id} (hash，完成后保留 7 天)
+//     fields: id, webhook_id, code, event, url, secret, payload, status, attempts,
+//     response_status, last_error, created_at, next_attempt_at, completed_at
+//   - 待投递队列：shortener:delivery:queue (sorted set，score 为下次可投递时间，进程重启后继续投递)
+//   - 投递日志：shortener:delivery:log:{webhook_id} (list，最近 100 条投递 ID)
+type RedisWebhookRepository struct {
+	rdb *redisv9.Client
+}
+
+func NewWebhookRepository(rdb *redisv9.Client) storage.WebhookRepository {
+	return &RedisWebhookRepository{rdb: rdb}
+}
+
+func (r *RedisWebhookRepository) CreateWebhook(ctx context.Context, hook *model.Webhook) error {
+	if hook.CreatedAt.IsZero() {
+		hook.CreatedAt = time.Now().UTC()
+	}
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redisv9.Pipeliner) error {
+		pipe.HSet(ctx, webhookKey(hook.ID), map[string]any{
+			"id":              hook.ID,
+			"code":            hook.Code,
+			"url":             hook.URL,
+			"secret":          hook.Secret,
+			"events":          strings.Join(hook.Events, ","),
+			"click_threshold": hook.ClickThreshold,
+			"created_at":      hook.CreatedAt.UTC().Format(time.RFC3339Nano),
+		})
+		pipe.ZAdd(ctx, "shortener:webhooks:"+hook.Code, redisv9.Z{
+			Score:  float64(hook.CreatedAt.UnixMilli()),
+			Member: hook.ID,
+		})
+		return nil
+	})
+	return err
+}
+
+func (r *RedisWebhookRepository) ListWebhooks(ctx context.Context, code string) ([]*model.Webhook, error) {
+	ids, err := r.rdb.ZRange(ctx, "shortener:webhooks:"+code, 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	cmds := make([]*redisv9.MapStringStringCmd, len(ids))
+	_, err = r.rdb.Pipelined(ctx, func(pipe redisv9.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.HGetAll(ctx, webhookKey(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hooks := make([]*model.Webhook, 0, len(ids))
+	for _, cmd := range cmds {
+		if m := cmd.Val(); len(m) > 0 {
+			hooks = append(hooks, parseWebhook(m))
+		}
+	}
+	return hooks, nil
+}
+
+func (r *RedisWebhookRepository) DeleteWebhook(ctx context.Context, code, id string) error {
+	removed, err := r.rdb.ZRem(ctx, "shortener:webhooks:"+code, id).Result()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return storage.ErrWebhookNotFound
+	}
+	return r.rdb.Del(ctx, webhookKey(id)).Err()
+}
+
+func (r *RedisWebhookRepository) DeleteWebhooks(ctx context.Context, code string) error {
+	listKey := "shortener:webhooks:" + code
+	ids, err := r.rdb.ZRange(ctx, listKey, 0, -1).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{listKey}
+	for _, id := range ids {
+		keys = append(keys, webhookKey(id))
+	}
+	return r.rdb.Del(ctx, keys...).Err()
+}
+
+func (r *RedisWebhookRepository) EnqueueDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now().UTC()
+	}
+	next := delivery.CreatedAt
+	if delivery.NextAttemptAt != nil {
+		next = *delivery.NextAttemptAt
+	}
+	logKey := deliveryLogKey(delivery.WebhookID)
+
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redisv9.Pipeliner) error {
+		pipe.HSet(ctx, deliveryKey(delivery.ID), deliveryFields(delivery))
+		pipe.ZAdd(ctx, deliveryQueueKey, redisv9.Z{Score: float64(next.UnixMilli()), Member: delivery.ID})
+		pipe.LPush(ctx, logKey, delivery.ID)
+		pipe.LTrim(ctx, logKey, 0, deliveryLogSize-1)
+		pipe.Expire(ctx, logKey, deliveryRetention)
+		return nil
+	})
+	return err
+}
+
+// claimDeliveriesScript 原子地取出到期的投递并推迟其下次可取出时间，多个实例同时轮询时不会重复投递
+var claimDeliveriesScript = redisv9.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[3])
+for _, id in ipairs(ids) do
+	redis.call('ZADD', KEYS[1], ARGV[2], id)
+end
+return ids
+`)
+
+func (r *RedisWebhookRepository) ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error) {
+	ids, err := claimDeliveriesScript.Run(ctx, r.rdb, []string{deliveryQueueKey},
+		now.UnixMilli(), now.Add(lease).UnixMilli(), limit).StringSlice()
+	if err != nil {
+		if errors.Is(err, redisv9.Nil) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	cmds := make([]*redisv9.MapStringStringCmd, len(ids))
+	_, err = r.rdb.Pipelined(ctx, func(pipe redisv9.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.HGetAll(ctx, deliveryKey(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*model.WebhookDelivery, 0, len(ids))
+	for i, cmd := range cmds {
+		m := cmd.Val()
+		if len(m) == 0 {
+			// 记录已丢失（不应发生），直接移出队列
+			_ = r.rdb.ZRem(ctx, deliveryQueueKey, ids[i]).Err()
+			continue
+		}
+		deliveries = append(deliveries, parseDelivery(m))
+	}
+	return deliveries, nil
+}
+
+func (r *RedisWebhookRepository) CompleteDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	key := deliveryKey(delivery.ID)
+	_, err := r.rdb.TxPipelined(ctx, func(pipe redisv9.Pipeliner) error {
+		pipe.HSet(ctx, key, deliveryFields(delivery))
+		if delivery.Status == model.DeliveryPending && delivery.NextAttemptAt != nil {
+			pipe.ZAdd(ctx, deliveryQueueKey, redisv9.Z{Score: float64(delivery.NextAttemptAt.UnixMilli()), Member: delivery.ID})
+			return nil
+		}
+		pipe.ZRem(ctx, deliveryQueueKey, delivery.ID)
+		pipe.Expire(ctx, key, deliveryRetention)
+		return nil
+	})
+	return err
+}
+
+func (r *RedisWebhookRepository) ListDeliveries(ctx context.Context, webhookID string, limit int) ([]*model.WebhookDelivery, error) {
+	ids, err := r.rdb.LRange(ctx, deliveryLogKey(webhookID), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	cmds := make([]*redisv9.MapStringStringCmd, len(ids))
+	_, err = r.rdb.Pipelined(ctx, func(pipe redisv9.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.HGetAll(ctx, deliveryKey(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*model.WebhookDelivery, 0, len(ids))
+	for _, cmd := range cmds {
+		// 已过保留期的记录直接跳过
+		if m := cmd.Val(); len(m) > 0 {
+			deliveries = append(deliveries, parseDelivery(m))
+		}
+	}
+	return deliveries, nil
+}
+
+func webhookKey(id string) string {
+	return "shortener:webhook:" + id
+}
+
+func deliveryKey(id string) string {
+	return "shortener:delivery:" + id
+}
+
+func deliveryLogKey(webhookID string) string {
+	return "shortener:delivery:log:" + webhookID
+}
+
+func parseWebhook(m map[string]string) *model.Webhook {
+	hook := &model.Webhook{
+		ID:     m["id"],
+		Code:   m["code"],
+		URL:    m["url"],
+		Secret: m["secret"],
+	}
+	if m["events"] != "" {
+		hook.Events = strings.Split(m["events"], ",")
+	}
+	hook.ClickThreshold, _ = strconv.ParseInt(m["click_threshold"], 10, 64)
+	hook.CreatedAt, _ = time.Parse(time.RFC3339Nano, m["created_at"])
+	return hook
+}
+
+// deliveryFields 将投递记录转换为 hash 字段，空的时间字段写入空字符串
+func deliveryFields(d *model.WebhookDelivery) map[string]any {
+	return map[string]any{
+		"id":              d.ID,
+		"webhook_id":      d.WebhookID,
+		"code":            d.Code,
+		"event":           d.Event,
+		"url":             d.URL,
+		"secret":          d.Secret,
+		"payload":         string(d.Payload),
+		"status":          d.Status,
+		"attempts":        d.Attempts,
+		"response_status": d.ResponseStatus,
+		"last_error":      d.LastError,
+		"created_at":      d.CreatedAt.UTC().Format(time.RFC3339Nano),
+		"next_attempt_at": formatOptionalTime(d.NextAttemptAt),
+		"completed_at":    formatOptionalTime(d.CompletedAt),
+	}
+}
+
+func parseDelivery(m map[string]string) *model.WebhookDelivery {
+	d := &model.WebhookDelivery{
+		ID:        m["id"],
+		WebhookID: m["webhook_id"],
+		Code:      m["code"],
+		Event:     m["event"],
+		URL:       m["url"],
+		Secret:    m["secret"],
+		Payload:   []byte(m["payload"]),
+		Status:    m["status"],
+		LastError: m["last_error"],
+	}
+	d.Attempts, _ = strconv.Atoi(m["attempts"])
+	d.ResponseStatus, _ = strconv.Atoi(m["response_status"])
+	d.CreatedAt, _ = time.Parse(time.RFC3339Nano, m["created_at"])
+	d.NextAttemptAt = parseOptionalTime(m["next_attempt_at"])
+	d.CompletedAt = parseOptionalTime(m["completed_at"])
+	return d
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func parseOptionalTime(raw string) *time.Time {
+	if raw == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, raw)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
@@ -2,19 +2,149 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"url-shortener/backend/internal/model"
 )
 
-// LinkRepository 定义短链接存储接口，方便未来替换实现（如 Redis/MySQL 等）
+var (
+	// ErrLinkNotFound 记录不存在（或已被 TTL 删除）
+	ErrLinkNotFound = errors.New("link not found")
+	// ErrClicksExhausted 点击次数已达到 max_clicks 上限
+	ErrClicksExhausted = errors.New("link click limit reached")
+	// ErrWebhookNotFound webhook 不存在（或不属于该短码）
+	ErrWebhookNotFound = errors.New("webhook not found")
+	// ErrAttributionNotFound 归因令牌不存在或已超过归因窗口
+	ErrAttributionNotFound = errors.New("attribution token not found")
+	// ErrTooManyGoals 归因令牌记录的转化目标数已达上限
+	ErrTooManyGoals = errors.New("attribution token goal limit reached")
+)
+
+// LinkRepository 定义短链接存储接口，方便未来替换实现（如 Redis/MySQL 等）。
+// 带 events 参数的写操作必须把事件与记录的修改在同一事务中写入 outbox：写入失败时两者都不生效
 type LinkRepository interface {
+	Outbox
 	// Create 保存新的短链接记录，并返回带 ID 的记录（如需生成短码，可在实现中分配 ID）
-	Create(ctx context.Context, link *model.ShortLink) (*model.ShortLink, error)
+	Create(ctx context.Context, link *model.ShortLink, events ...*model.DomainEvent) (*model.ShortLink, error)
 	// GetByCode 根据短码查询
 	GetByCode(ctx context.Context, code string) (*model.ShortLink, error)
-	// IncrementClick 在访问时增加点击次数并更新 last_accessed_at
-	IncrementClick(ctx context.Context, code string) error
+	// IncrementClick 在访问时原子地增加点击次数并更新 last_accessed_at，返回累加后的点击次数；
+	// variant 非空时同时累加该 A/B 分组的点击次数。
+	// 若记录设置了 max_clicks 且人工点击与机器人访问合计已达到上限，则不累加并返回 ErrClicksExhausted
+	IncrementClick(ctx context.Context, code, variant string, events ...*model.DomainEvent) (int64, error)
+	// IncrementBotClick 累加机器人访问次数（不计入 click_count，但同样占用 max_clicks），
+	// 已达到上限时返回 ErrClicksExhausted，记录不存在时返回 ErrLinkNotFound
+	IncrementBotClick(ctx context.Context, code string) error
+	// Update 保存可修改的字段（long_url、expire_at、fallback_url、inactive_message、max_clicks）
+	// 并按 expire_at 调整记录的保留时长，记录不存在时返回 ErrLinkNotFound
+	Update(ctx context.Context, link *model.ShortLink, events ...*model.DomainEvent) error
+	// Delete 删除短链接记录，记录不存在时返回 ErrLinkNotFound
+	Delete(ctx context.Context, code string, events ...*model.DomainEvent) error
+	// ClaimExpired 领取最多 limit 个 expire_at 不晚于 now 的短码，until 之前其他调用方不会再领取到它们；
+	// 处理完成后需调用 AckExpired，未确认的短码在 until 之后会被重新领取
+	ClaimExpired(ctx context.Context, now, until time.Time, limit int) ([]string, error)
+	// AckExpired 将领取的短码移出过期队列并写入事件；领取后 expire_at 被修改（重新入队）
+	// 或记录被删除时不做任何修改
+	AckExpired(ctx context.Context, code string, until time.Time, events ...*model.DomainEvent) error
+	// BackfillExpiring 将过期队列上线前创建、尚未到期的短码加入过期队列（完成后不再重复执行）
+	BackfillExpiring(ctx context.Context, now time.Time) error
+	// SetDisabled 停用或重新启用短链接，记录不存在时返回 ErrLinkNotFound
+	SetDisabled(ctx context.Context, code string, disabled bool, events ...*model.DomainEvent) error
+	// UpdateMetadata 保存抓取到的目标页面元数据，记录不存在时返回 ErrLinkNotFound
+	UpdateMetadata(ctx context.Context, code string, metadata *model.PageMetadata) error
+	// NextRoundRobin 原子地递增短码的轮询计数器并返回递增后的值（从 1 开始），
+	// 记录不存在时返回 ErrLinkNotFound
+	NextRoundRobin(ctx context.Context, code string) (int64, error)
+	// SetHealth 保存目标地址的健康检查结果，destinationID 为空表示主链接（long_url），
+	// 语言路由目标使用 model.LanguageHealthID(tag)；
+	// 记录不存在时返回 ErrLinkNotFound
+	SetHealth(ctx context.Context, code, destinationID string, status *model.HealthStatus) error
+	// SetBroken 将短码加入或移出失效链接集合
+	SetBroken(ctx context.Context, code string, broken bool) error
+	// ListBroken 返回失效链接集合中的所有短码
+	ListBroken(ctx context.Context) ([]string, error)
+	// ForEachLink 遍历所有短链接记录，fn 返回错误时停止遍历并返回该错误
+	ForEachLink(ctx context.Context, fn func(link *model.ShortLink) error) error
 	// NextID 获取全局自增 ID（用于生成短码）
 	NextID(ctx context.Context) (int64, error)
+	// IncrementPasswordAttempts 累加短码在当前时间窗口内的密码尝试次数（窗口从第一次尝试开始计时）
+	IncrementPasswordAttempts(ctx context.Context, code string, window time.Duration) (int64, error)
+	// DecrementPasswordAttempts 撤销一次密码尝试计数（密码正确时调用，窗口内只保留失败次数）
+	DecrementPasswordAttempts(ctx context.Context, code string) error
+}
+
+// Outbox 定义领域事件 outbox 的读取接口：每个消费组（sink）独立读取全部事件，
+// 读取后需调用 AckOutbox 确认，未确认的事件会再次返回
+type Outbox interface {
+	// ReadOutbox 读取消费组 group 中最多 count 个事件：先返回读取后长时间未确认的事件
+	// （如消费者在确认前崩溃），再返回新事件；没有事件时最多阻塞 block
+	ReadOutbox(ctx context.Context, group, consumer string, count int, block time.Duration) ([]*model.DomainEvent, error)
+	// AckOutbox 确认消费组已处理完这些事件
+	AckOutbox(ctx context.Context, group string, ids ...string) error
+	// TrimOutbox 删除 groups 中所有消费组都已确认的事件，返回删除的条数；
+	// 任一消费组尚未创建时不删除
+	TrimOutbox(ctx context.Context, groups []string) (int64, error)
+	// PurgeOutbox 删除早于 before 的事件（无论是否已确认），返回删除的条数
+	PurgeOutbox(ctx context.Context, before time.Time) (int64, error)
 }
 
+// ClickRepository 定义原始点击事件的存储接口
+type ClickRepository interface {
+	// CountClick 在重定向时同步累加 at 所在小时桶与天桶的点击数（bot 为 true 时累加 bot_clicks），
+	// 人工点击同时计入排行榜
+	CountClick(ctx context.Context, code string, bot bool, at time.Time) error
+	// RecordClick 追加一条点击事件并累加来源排行、独立访客等明细统计，成功后回填 event.ID；
+	// event.NoTrack 为 true 时不写入任何数据（计数已由 CountClick 累加）
+	RecordClick(ctx context.Context, event *model.ClickEvent) error
+	// ClickStats 返回 [from, to] 内按 interval（hour/day）划分的点击统计，
+	// from 需已对齐到桶起点，没有点击的桶计为 0
+	ClickStats(ctx context.Context, code, interval string, from, to time.Time) ([]model.StatsBucket, error)
+	// UniqueVisitors 返回短码的总独立访客数（HyperLogLog 估算）
+	UniqueVisitors(ctx context.Context, code string) (int64, error)
+	// UniqueVisitorsBetween 返回 [from, to] 覆盖的各天（UTC）合并后的独立访客数
+	UniqueVisitorsBetween(ctx context.Context, code string, from, to time.Time) (int64, error)
+	// Breakdown 返回某个维度点击数最多的前 limit 个取值（降序）及该维度的总点击数
+	Breakdown(ctx context.Context, code, dimension string, limit int) ([]model.BreakdownItem, int64, error)
+	// TopLinks 返回滚动窗口（1h/24h/7d）内点击数最多的前 limit 个短码（降序）
+	TopLinks(ctx context.Context, window string, limit int) ([]model.RankedLink, error)
+	// ForEachClick 按时间顺序分页遍历 [from, to] 内的原始点击事件（from 为零值时从最早的事件开始），fn 返回错误时停止遍历
+	ForEachClick(ctx context.Context, code string, from, to time.Time, fn func(event *model.ClickEvent) error) error
+	// DeleteClicks 删除短码的原始点击事件、时间桶、来源排行、独立访客与转化汇总
+	DeleteClicks(ctx context.Context, code string) error
+	// TrimClicks 删除所有短码中早于 before 的原始点击事件，返回删除的条数
+	TrimClicks(ctx context.Context, before time.Time) (int64, error)
+	// DailySalt 返回 day（UTC）所在天的随机密钥，所有实例共享，过期后自动删除
+	DailySalt(ctx context.Context, day time.Time) ([]byte, error)
+	// SubscribeClicks 订阅所有实例记录的实时点击事件（不含 IP），ctx 结束时关闭返回的通道
+	SubscribeClicks(ctx context.Context) (<-chan *model.ClickEvent, error)
+	// CreateAttribution 保存重定向签发的归因令牌，ttl 为归因窗口
+	CreateAttribution(ctx context.Context, attr *model.Attribution, ttl time.Duration) error
+	// RecordConversion 记录一次转化并回填 conv.Code / conv.Variant；
+	// 令牌不存在时返回 ErrAttributionNotFound，同一令牌的同一目标只计一次（重复时返回 false），
+	// 令牌的目标数已达上限时返回 ErrTooManyGoals
+	RecordConversion(ctx context.Context, conv *model.Conversion) (bool, error)
+	// ConversionTotals 返回短码的转化总数及各目标的转化数
+	ConversionTotals(ctx context.Context, code string) (int64, map[string]int64, error)
+}
+
+// WebhookRepository 定义 webhook 注册信息与持久化投递队列的存储接口
+type WebhookRepository interface {
+	// CreateWebhook 保存新的 webhook
+	CreateWebhook(ctx context.Context, hook *model.Webhook) error
+	// ListWebhooks 返回短码上注册的所有 webhook（按创建时间升序）
+	ListWebhooks(ctx context.Context, code string) ([]*model.Webhook, error)
+	// DeleteWebhook 删除短码上的某个 webhook，不存在时返回 ErrWebhookNotFound
+	DeleteWebhook(ctx context.Context, code, id string) error
+	// DeleteWebhooks 删除短码上的所有 webhook（已入队的投递不受影响）
+	DeleteWebhooks(ctx context.Context, code string) error
+	// EnqueueDelivery 保存一条待投递记录，在 delivery.NextAttemptAt 到达后可被 ClaimDeliveries 取出
+	EnqueueDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	// ClaimDeliveries 取出最多 limit 条已到期的待投递记录，并将其下次可取出时间推迟 lease；
+	// 取出后未调用 CompleteDelivery（如进程崩溃）的记录会在 lease 之后重新被取出
+	ClaimDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*model.WebhookDelivery, error)
+	// CompleteDelivery 保存一次投递尝试的结果：状态仍为 pending 时按 NextAttemptAt 重新排队，否则移出队列
+	CompleteDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+	// ListDeliveries 返回 webhook 最近的投递记录（新的在前）
+	ListDeliveries(ctx context.Context, webhookID string, limit int) ([]*model.WebhookDelivery, error)
+}
@@ -12,4 +12,22 @@ var (
 	ErrCodeTooLong   = errors.New("code exceeds maximum length of 32 characters")
 	ErrInvalidCode   = errors.New("code contains invalid characters, only alphanumeric allowed")
 	ErrCodeAllDigits = errors.New("code cannot be all digits")
+
+	ErrTooManyDestinations = errors.New("destinations exceed maximum of 10 entries")
+	ErrInvalidWeight       = errors.New("destination weight must be between 1 and 1000")
+	ErrInvalidVariantID    = errors.New("destination id must be 1-16 alphanumeric characters")
+	ErrDuplicateVariantID  = errors.New("destination id must be unique")
+	ErrTooManyLanguages    = errors.New("language_urls exceed maximum of 50 entries")
+	ErrInvalidLanguageTag  = errors.New("language tag is invalid, expected format like en or zh-CN")
+	ErrInvalidTimezone     = errors.New("schedule timezone is invalid")
+	ErrEmptySchedule       = errors.New("schedule must contain at least one window")
+	ErrTooManyWindows      = errors.New("schedule windows exceed maximum of 20 entries")
+	ErrInvalidWindowTime   = errors.New("schedule window start/end must be in HH:MM format and differ")
+	ErrInvalidWindowDay    = errors.New("schedule window days must be mon-sun, weekdays or weekends")
+	ErrActivateAfterExpire = errors.New("activate_at must be before expire_at")
+	ErrPasswordTooShort    = errors.New("password must be at least 4 characters")
+	ErrPasswordTooLong     = errors.New("password exceeds maximum length of 72 bytes")
+	ErrInvalidAppURL       = errors.New("app url must be a uri with a custom scheme, e.g. myapp://path")
+	ErrInvalidAppScheme    = errors.New("app url scheme is not allowed, use url for http/https")
+	ErrInvalidGoal         = errors.New("conversion goal must be 1-64 characters of letters, digits, _ . -")
 )
@@ -0,0 +1,110 @@
+package util
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// LanguageRange Accept-Language 中的一个语言范围及其权重
+type LanguageRange struct {
+	Tag     string
+	Quality float64
+}
+
+// ParseAcceptLanguage 解析 Accept-Language 头，按 q 值从高到低排序（同权重保持原顺序），忽略 q=0 的项
+func ParseAcceptLanguage(header string) []LanguageRange {
+	var ranges []LanguageRange
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || strings.TrimSpace(name) != "q" {
+				continue
+			}
+			q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil || q < 0 || q > 1 {
+				q = 0
+			}
+			quality = q
+		}
+		if quality == 0 {
+			continue
+		}
+		ranges = append(ranges, LanguageRange{Tag: tag, Quality: quality})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].Quality > ranges[j].Quality
+	})
+	return ranges
+}
+
+// MatchLanguage 按 Accept-Language 从 available 中选择最合适的语言标签，无匹配时返回空串。
+// 对每个语言范围依次尝试：完全匹配、前缀匹配（en 匹配 en-US）、逐级截断回退（zh-Hant-TW -> zh-Hant -> zh）
+func MatchLanguage(header string, available []string) string {
+	if len(available) == 0 {
+		return ""
+	}
+	for _, r := range ParseAcceptLanguage(header) {
+		if r.Tag == "*" {
+			continue
+		}
+		for _, tag := range available {
+			if strings.EqualFold(tag, r.Tag) {
+				return tag
+			}
+		}
+		for _, tag := range available {
+			if hasLanguagePrefix(tag, r.Tag) {
+				return tag
+			}
+		}
+		for prefix := r.Tag; ; {
+			i := strings.LastIndex(prefix, "-")
+			if i <= 0 {
+				break
+			}
+			prefix = prefix[:i]
+			for _, tag := range available {
+				if strings.EqualFold(tag, prefix) {
+					return tag
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// ValidateLanguageTag 验证语言标签格式（如 en、zh-CN、zh-Hant-TW）
+func ValidateLanguageTag(tag string) error {
+	if tag == "" || len(tag) > 35 {
+		return ErrInvalidLanguageTag
+	}
+	for i, sub := range strings.Split(tag, "-") {
+		if sub == "" || len(sub) > 8 {
+			return ErrInvalidLanguageTag
+		}
+		for _, char := range sub {
+			isLetter := (char >= 'A' && char <= 'Z') || (char >= 'a' && char <= 'z')
+			isDigit := char >= '0' && char <= '9'
+			// 主语言子标签只能是字母
+			if !isLetter && (i == 0 || !isDigit) {
+				return ErrInvalidLanguageTag
+			}
+		}
+	}
+	return nil
+}
+
+func hasLanguagePrefix(tag, prefix string) bool {
+	return len(tag) > len(prefix) && tag[len(prefix)] == '-' && strings.EqualFold(tag[:len(prefix)], prefix)
+}
@@ -0,0 +1,59 @@
+package util
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []LanguageRange
+	}{
+		{"empty", "", nil},
+		{"single", "en", []LanguageRange{{"en", 1}}},
+		{"sorted by quality", "en;q=0.5, zh-CN, fr;q=0.8", []LanguageRange{{"zh-CN", 1}, {"fr", 0.8}, {"en", 0.5}}},
+		{"stable for equal quality", "de, en, fr", []LanguageRange{{"de", 1}, {"en", 1}, {"fr", 1}}},
+		{"drops q=0", "en;q=0, fr", []LanguageRange{{"fr", 1}}},
+		{"drops invalid q", "en;q=abc, fr;q=2, de;q=0.3", []LanguageRange{{"de", 0.3}}},
+		{"skips empty parts", " , en ,, ", []LanguageRange{{"en", 1}}},
+		{"wildcard kept", "*;q=0.1, en", []LanguageRange{{"en", 1}, {"*", 0.1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseAcceptLanguage(tt.header)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseAcceptLanguage(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchLanguage(t *testing.T) {
+	available := []string{"en-US", "zh-Hant", "zh", "fr"}
+	tests := []struct {
+		name      string
+		header    string
+		available []string
+		want      string
+	}{
+		{"exact case-insensitive", "EN-us", available, "en-US"},
+		{"prefix match", "en", available, "en-US"},
+		{"truncation fallback", "zh-Hant-TW", available, "zh-Hant"},
+		{"truncation to primary", "zh-CN", available, "zh"},
+		{"quality order", "de, fr;q=0.9, en;q=0.8", available, "fr"},
+		{"wildcard ignored", "*", available, ""},
+		{"no match", "ja, ko", available, ""},
+		{"empty header", "", available, ""},
+		{"no available", "en", nil, ""},
+		{"prefix needs separator", "e", []string{"en"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchLanguage(tt.header, tt.available); got != tt.want {
+				t.Errorf("MatchLanguage(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
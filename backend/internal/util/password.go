@@ -0,0 +1,28 @@
+package util
+
+import "golang.org/x/crypto/bcrypt"
+
+// ValidatePassword 验证访问密码长度（bcrypt 最多使用 72 字节）
+func ValidatePassword(password string) error {
+	if len(password) < 4 {
+		return ErrPasswordTooShort
+	}
+	if len(password) > 72 {
+		return ErrPasswordTooLong
+	}
+	return nil
+}
+
+// HashPassword 使用 bcrypt 生成带随机盐的密码哈希
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// CheckPassword 校验密码是否与哈希匹配
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
@@ -0,0 +1,96 @@
+package util
+
+import (
+	"strings"
+
+	"url-shortener/backend/internal/model"
+)
+
+// unfurlBotPatterns 常见社交平台/IM 链接预览抓取器的 User-Agent 特征（小写）
+var unfurlBotPatterns = []string{
+	"slackbot",
+	"slack-imgproxy",
+	"twitterbot",
+	"facebookexternalhit",
+	"facebookcatalog",
+	"linkedinbot",
+	"discordbot",
+	"telegrambot",
+	"whatsapp",
+	"skypeuripreview",
+	"redditbot",
+	"pinterestbot",
+	"vkshare",
+	"embedly",
+	"iframely",
+	"mattermost",
+	"microsoft teams",
+}
+
+// IsUnfurlBot 判断 User-Agent 是否为社交平台的链接预览抓取器
+func IsUnfurlBot(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return false
+	}
+	for _, pattern := range unfurlBotPatterns {
+		if strings.Contains(ua, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// MobilePlatform 根据 User-Agent 判断移动平台，返回 "ios"、"android" 或空串
+func MobilePlatform(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case strings.Contains(ua, "android"):
+		return "android"
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return "ios"
+	}
+	return ""
+}
+
+// UserAgentClass 根据 User-Agent 粗略分类访问设备：tablet、mobile、desktop 或 unknown。
+// 机器人识别由 botdetect.Classifier 负责
+func UserAgentClass(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	if ua == "" {
+		return model.UAClassUnknown
+	}
+	switch {
+	case strings.Contains(ua, "ipad"), strings.Contains(ua, "tablet"),
+		strings.Contains(ua, "android") && !strings.Contains(ua, "mobile"):
+		return model.UAClassTablet
+	case strings.Contains(ua, "mobi"), strings.Contains(ua, "iphone"), strings.Contains(ua, "ipod"):
+		return model.UAClassMobile
+	case strings.Contains(ua, "windows"), strings.Contains(ua, "macintosh"),
+		strings.Contains(ua, "x11"), strings.Contains(ua, "cros"):
+		return model.UAClassDesktop
+	}
+	return model.UAClassUnknown
+}
+
+// Browser 根据 User-Agent 识别浏览器家族，顺序很重要：Edge/Opera/Samsung 的 UA 同样包含 Chrome 与 Safari
+func Browser(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return "other"
+	case strings.Contains(ua, "edg/"), strings.Contains(ua, "edga/"), strings.Contains(ua, "edgios/"):
+		return "edge"
+	case strings.Contains(ua, "opr/"), strings.Contains(ua, "opera"):
+		return "opera"
+	case strings.Contains(ua, "samsungbrowser/"):
+		return "samsung"
+	case strings.Contains(ua, "firefox/"), strings.Contains(ua, "fxios/"):
+		return "firefox"
+	case strings.Contains(ua, "chrome/"), strings.Contains(ua, "crios/"), strings.Contains(ua, "chromium/"):
+		return "chrome"
+	case strings.Contains(ua, "safari/"):
+		return "safari"
+	}
+	return "other"
+}
@@ -27,6 +27,44 @@ func ValidateURL(rawURL string) error {
 	return nil
 }
 
+// blockedAppSchemes 不允许作为 App 深度链接的 scheme（网页地址走 url 字段，其余可执行脚本或读取本地资源）
+var blockedAppSchemes = map[string]bool{
+	"http":       true,
+	"https":      true,
+	"javascript": true,
+	"vbscript":   true,
+	"data":       true,
+	"file":       true,
+	"blob":       true,
+	"about":      true,
+}
+
+// ValidateAppURL 验证 App 深度链接（如 myapp://product/42），scheme 必须是自定义协议
+func ValidateAppURL(rawURL string) error {
+	if rawURL == "" {
+		return ErrEmptyURL
+	}
+	if len(rawURL) > 2048 {
+		return ErrURLTooLong
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Scheme == "" {
+		return ErrInvalidAppURL
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	if blockedAppSchemes[scheme] {
+		return ErrInvalidAppScheme
+	}
+	for i, char := range scheme {
+		isLetter := char >= 'a' && char <= 'z'
+		isOther := (char >= '0' && char <= '9') || char == '+' || char == '-' || char == '.'
+		if !isLetter && (i == 0 || !isOther) {
+			return ErrInvalidAppURL
+		}
+	}
+	return nil
+}
+
 // ValidateCode 验证自定义短码是否合法（仅允许字母数字，长度限制）
 func ValidateCode(code string) error {
 	if code == "" {
@@ -54,3 +92,34 @@ func ValidateCode(code string) error {
 	}
 	return nil
 }
+
+// ValidateVariantID 验证 A/B 分组 ID 是否合法（1~16 位字母数字，会写入 cookie 与 Redis 字段名）
+func ValidateVariantID(id string) error {
+	if id == "" || len(id) > 16 {
+		return ErrInvalidVariantID
+	}
+	for _, char := range id {
+		if !((char >= '0' && char <= '9') ||
+			(char >= 'A' && char <= 'Z') ||
+			(char >= 'a' && char <= 'z')) {
+			return ErrInvalidVariantID
+		}
+	}
+	return nil
+}
+
+// ValidateGoal 验证转化目标名是否合法（1~64 位字母数字及 _ . -，会写入 Redis 字段名）
+func ValidateGoal(goal string) error {
+	if goal == "" || len(goal) > 64 {
+		return ErrInvalidGoal
+	}
+	for _, char := range goal {
+		if !((char >= '0' && char <= '9') ||
+			(char >= 'A' && char <= 'Z') ||
+			(char >= 'a' && char <= 'z') ||
+			char == '_' || char == '.' || char == '-') {
+			return ErrInvalidGoal
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// TruncateIP 截断访问者 IP：IPv4 保留前 v4Bits 位，IPv6 保留前 v6Bits 位；无法解析时返回空串
+func TruncateIP(raw string, v4Bits, v6Bits int) string {
+	ip := net.ParseIP(strings.TrimSpace(raw))
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(v4Bits, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(v6Bits, 128)).String()
+}
+
+// HashIP 用带密钥的 HMAC-SHA256 哈希访客 IP（截取 16 字节）；无法解析时返回空串
+func HashIP(salt []byte, raw string) string {
+	ip := net.ParseIP(strings.TrimSpace(raw))
+	if ip == nil {
+		return ""
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(ip.String()))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// ReferrerHost 提取 Referer 的域名（小写，去掉端口）；无效或非 http(s) 地址返回空串
+func ReferrerHost(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+	u, err := url.Parse(referrer)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// VisitorFingerprint 用带密钥的 HMAC-SHA256 对 IP 与 User-Agent 计算访客指纹（截取 16 字节），
+// 无法从指纹还原 IP；IP 为空时返回空串
+func VisitorFingerprint(salt []byte, ip, userAgent string) string {
+	ip = strings.TrimSpace(ip)
+	if ip == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(ip))
+	mac.Write([]byte{0})
+	mac.Write([]byte(userAgent))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// NormalizeCountry 规范化两位国家代码（大写）；无效值及 Cloudflare 的 XX/T1 返回空串
+func NormalizeCountry(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 2 || code == "XX" || code == "T1" {
+		return ""
+	}
+	for _, char := range code {
+		if char < 'A' || char > 'Z' {
+			return ""
+		}
+	}
+	return code
+}
@@ -0,0 +1,49 @@
+package util
+
+import "testing"
+
+func TestTruncateIP(t *testing.T) {
+	tests := []struct {
+		raw    string
+		v4Bits int
+		v6Bits int
+		want   string
+	}{
+		{"203.0.113.77", 24, 48, "203.0.113.0"},
+		{"203.0.113.77", 16, 48, "203.0.0.0"},
+		{"203.0.113.77", 32, 48, "203.0.113.77"},
+		{" 198.51.100.9 ", 24, 48, "198.51.100.0"},
+		{"::ffff:203.0.113.77", 24, 48, "203.0.113.0"},
+		{"2001:db8:abcd:12:34::1", 24, 48, "2001:db8:abcd::"},
+		{"2001:db8:abcd:12:34::1", 24, 64, "2001:db8:abcd:12::"},
+		{"not-an-ip", 24, 48, ""},
+		{"", 24, 48, ""},
+	}
+	for _, tt := range tests {
+		if got := TruncateIP(tt.raw, tt.v4Bits, tt.v6Bits); got != tt.want {
+			t.Errorf("TruncateIP(%q, %d, %d) = %q, want %q", tt.raw, tt.v4Bits, tt.v6Bits, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeCountry(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"US", "US"},
+		{"cn", "CN"},
+		{" de ", "DE"},
+		{"XX", ""},
+		{"t1", ""},
+		{"USA", ""},
+		{"U", ""},
+		{"1A", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeCountry(tt.code); got != tt.want {
+			t.Errorf("NormalizeCountry(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"url-shortener/backend/internal/model"
+	"url-shortener/backend/internal/safehttp"
+	"url-shortener/backend/internal/storage"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultConcurrency  = 4
+	defaultTimeout      = 10 * time.Second
+	defaultMaxAttempts  = 10
+	// baseBackoff / maxBackoff 第 n 次失败后等待 baseBackoff * 2^(n-1)，最长 maxBackoff
+	baseBackoff = 30 * time.Second
+	maxBackoff  = time.Hour
+	// maxErrorLength 投递日志中保存的失败原因最大长度
+	maxErrorLength = 256
+)
+
+// 请求头：接收方用 SignatureHeader 校验请求来源，用 DeliveryHeader 去重
+const (
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+// Options 投递配置，零值字段使用默认值
+type Options struct {
+	// PollInterval 队列为空时轮询的间隔
+	PollInterval time.Duration
+	// Concurrency 同时进行的投递数上限
+	Concurrency int
+	// Timeout 单次投递超时
+	Timeout time.Duration
+	// MaxAttempts 最多尝试次数，全部失败后标记为 failed
+	MaxAttempts int
+	// AllowPrivateNetworks 允许推送到内网地址（接收方部署在内网时开启）
+	AllowPrivateNetworks bool
+}
+
+// Dispatcher 从持久化队列中取出到期的投递并推送，失败后按指数退避重新排队。
+// 队列保存在存储中，多个实例可以同时运行，进程重启后未完成的投递会继续重试
+type Dispatcher struct {
+	repo         storage.WebhookRepository
+	client       *http.Client
+	pollInterval time.Duration
+	concurrency  int
+	maxAttempts  int
+	// lease 取出后独占的时长，超过后（如进程在投递中崩溃）会被重新取出
+	lease time.Duration
+}
+
+func NewDispatcher(repo storage.WebhookRepository, opts Options) *Dispatcher {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultConcurrency
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	client := safehttp.NewClient(safehttp.Options{
+		Timeout:              opts.Timeout,
+		AllowPrivateNetworks: opts.AllowPrivateNetworks,
+	})
+	// 不跟随重定向：3xx 视为投递失败，避免 POST 被改写为 GET
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &Dispatcher{
+		repo:         repo,
+		client:       client,
+		pollInterval: opts.PollInterval,
+		concurrency:  opts.Concurrency,
+		maxAttempts:  opts.MaxAttempts,
+		lease:        2*opts.Timeout + opts.PollInterval,
+	}
+}
+
+// Run 持续投递队列中到期的记录，直到 ctx 取消
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// 取满一批时说明队列还有积压，立即继续
+		if d.dispatchBatch(ctx) == d.concurrency && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchBatch 取出一批到期的投递并发推送，返回本批条数
+func (d *Dispatcher) dispatchBatch(ctx context.Context) int {
+	deliveries, err := d.repo.ClaimDeliveries(ctx, time.Now(), d.lease, d.concurrency)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("failed to claim webhook deliveries: %v", err)
+		}
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *model.WebhookDelivery) {
+			defer wg.Done()
+			d.deliver(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries)
+}
+
+// deliver 推送一次并保存结果：2xx 为成功，否则按退避时间重新排队，达到最大次数后放弃
+func (d *Dispatcher) deliver(ctx context.Context, delivery *model.WebhookDelivery) {
+	statusCode, err := d.post(ctx, delivery)
+	if ctx.Err() != nil {
+		// 进程退出导致的失败不计入尝试次数，lease 到期后由其他实例或重启后的进程继续投递
+		return
+	}
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.ResponseStatus = statusCode
+	delivery.LastError = ""
+	delivery.NextAttemptAt = nil
+	switch {
+	case err == nil:
+		delivery.Status = model.DeliverySucceeded
+		delivery.CompletedAt = &now
+	case delivery.Attempts >= d.maxAttempts:
+		delivery.Status = model.DeliveryFailed
+		delivery.LastError = truncateError(err)
+		delivery.CompletedAt = &now
+	default:
+		next := now.Add(backoff(delivery.Attempts))
+		delivery.Status = model.DeliveryPending
+		delivery.LastError = truncateError(err)
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := d.repo.CompleteDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		log.Printf("failed to save webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// post 发送签名后的事件，返回响应状态码（请求失败时为 0）
+func (d *Dispatcher) post(ctx context.Context, delivery *model.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "url-shortener-webhook/1.0")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// 读完（有限长度的）响应体以便复用连接
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign 计算请求签名：sha256=hex(HMAC-SHA256(secret, "{timestamp}.{body}"))。
+// 签名包含时间戳，接收方可拒绝时间戳过旧的请求以防重放
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff 返回第 attempts 次失败后的等待时间
+func backoff(attempts int) time.Duration {
+	wait := baseBackoff
+	for i := 1; i < attempts && wait < maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+	return wait
+}
+
+func truncateError(err error) string {
+	msg := err.Error()
+	if len(msg) > maxErrorLength {
+		msg = msg[:maxErrorLength]
+	}
+	return msg
+}
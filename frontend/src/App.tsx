@@ -1,4 +1,4 @@
-import React, { useState } from "react";
+import React, { useEffect, useState } from "react";
 
 type ShortenResponse = {
   code: string;
@@ -7,13 +7,36 @@ type ShortenResponse = {
   expire_at?: string | null;
 };
 
+type PageMetadata = {
+  title?: string;
+  description?: string;
+  image?: string;
+  final_url?: string;
+  fetched_at: string;
+  error?: string;
+};
+
 type LinkInfoResponse = {
   code: string;
-  long_url: string;
+  long_url?: string;
   created_at: string;
   expire_at?: string | null;
   click_count: number;
+  unique_visitors?: number;
+  unique_visitors_today?: number;
+  track_conversions?: boolean;
+  conversion_count?: number;
   last_accessed_at?: string | null;
+  password_protected?: boolean;
+  metadata?: PageMetadata | null;
+};
+
+type ClickEvent = {
+  code: string;
+  timestamp: string;
+  referrer_host?: string;
+  ua_class: string;
+  bot: boolean;
 };
 
 const API_BASE_URL =
@@ -30,6 +53,30 @@ export const App: React.FC = () => {
   const [infoLoading, setInfoLoading] = useState(false);
   const [infoError, setInfoError] = useState<string | null>(null);
   const [infoResult, setInfoResult] = useState<LinkInfoResponse | null>(null);
+  const [liveClicks, setLiveClicks] = useState(0);
+  const [liveConnected, setLiveConnected] = useState(false);
+
+  // 查询到短链接后订阅实时点击流，在查询结果的基础上累加人工点击；
+  // 受密码保护的链接需要密码才能订阅（EventSource 无法携带请求头），不订阅
+  const liveCode = infoResult?.password_protected ? undefined : infoResult?.code;
+  useEffect(() => {
+    setLiveClicks(0);
+    setLiveConnected(false);
+    if (!liveCode) {
+      return;
+    }
+
+    const source = new EventSource(`${API_BASE_URL}/api/v1/links/${liveCode}/live`);
+    source.onopen = () => setLiveConnected(true);
+    source.onerror = () => setLiveConnected(false);
+    source.addEventListener("click", (e) => {
+      const event: ClickEvent = JSON.parse((e as MessageEvent).data);
+      if (!event.bot) {
+        setLiveClicks((n) => n + 1);
+      }
+    });
+    return () => source.close();
+  }, [liveCode]);
 
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
@@ -183,12 +230,57 @@ export const App: React.FC = () => {
                 {`${API_BASE_URL}/${infoResult.code}`}
               </a>
             </p>
-            <p className="muted">原始链接：{infoResult.long_url}</p>
+            {infoResult.metadata && !infoResult.metadata.error && (
+              <div className="link-preview">
+                {infoResult.metadata.image && (
+                  <img
+                    className="link-preview-image"
+                    src={infoResult.metadata.image}
+                    alt=""
+                  />
+                )}
+                <div>
+                  <p className="link-preview-title">
+                    {infoResult.metadata.title || infoResult.long_url}
+                  </p>
+                  {infoResult.metadata.description && (
+                    <p className="muted">{infoResult.metadata.description}</p>
+                  )}
+                </div>
+              </div>
+            )}
+            <p className="muted">
+              原始链接：
+              {infoResult.password_protected && !infoResult.long_url
+                ? "受密码保护"
+                : infoResult.long_url}
+            </p>
+            {infoResult.metadata?.final_url &&
+              infoResult.metadata.final_url !== infoResult.long_url && (
+                <p className="muted">最终地址：{infoResult.metadata.final_url}</p>
+              )}
             <p className="muted">
               过期时间：{infoResult.expire_at ? infoResult.expire_at : "永不过期"}
             </p>
             <p className="muted">创建时间：{infoResult.created_at}</p>
-            <p className="muted">点击次数：{infoResult.click_count}</p>
+            <p className="muted">
+              点击次数：{infoResult.click_count + liveClicks}
+              {liveConnected && <span className="live-badge">实时</span>}
+            </p>
+            <p className="muted">
+              独立访客：{infoResult.unique_visitors ?? 0}（今日{" "}
+              {infoResult.unique_visitors_today ?? 0}）
+            </p>
+            {infoResult.track_conversions && (
+              <p className="muted">
+                转化次数：{infoResult.conversion_count ?? 0}
+                {infoResult.click_count > 0 &&
+                  `（转化率 ${(
+                    ((infoResult.conversion_count ?? 0) / infoResult.click_count) *
+                    100
+                  ).toFixed(1)}%）`}
+              </p>
+            )}
             <p className="muted">
               最后访问：{infoResult.last_accessed_at || "尚无访问记录"}
             </p>